@@ -10,6 +10,7 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position
 }
 
 type Statement interface {
@@ -34,6 +35,13 @@ func (p *Program) TokenLiteral() string {
 	}
 }
 
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
 func (p *Program) String() string {
 	var out bytes.Buffer
 	for _, s := range p.Statements {
@@ -57,9 +65,11 @@ func (i *Identifier) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (i *Identifier) Pos() token.Position { return i.Token.Pos }
+
 type LetStatement struct {
 	Token token.Token
-	Name  *Identifier
+	Name  Pattern
 	Value Expression
 }
 
@@ -78,6 +88,8 @@ func (ls *LetStatement) TokenLiteral() string {
 	return ls.Token.Literal
 }
 
+func (ls *LetStatement) Pos() token.Position { return ls.Token.Pos }
+
 type ReturnStatement struct {
 	Token       token.Token
 	ReturnValue Expression
@@ -98,6 +110,8 @@ func (rs *ReturnStatement) TokenLiteral() string {
 	return rs.Token.Literal
 }
 
+func (rs *ReturnStatement) Pos() token.Position { return rs.Token.Pos }
+
 type ExpressionStatement struct {
 	Token      token.Token
 	Expression Expression
@@ -116,6 +130,8 @@ func (es *ExpressionStatement) TokenLiteral() string {
 	return es.Token.Literal
 }
 
+func (es *ExpressionStatement) Pos() token.Position { return es.Token.Pos }
+
 type BooleanLiteral struct {
 	Token token.Token
 	Value bool
@@ -133,6 +149,8 @@ func (b *BooleanLiteral) TokenLiteral() string {
 	return b.Token.Literal
 }
 
+func (b *BooleanLiteral) Pos() token.Position { return b.Token.Pos }
+
 type IntegerLiteral struct {
 	Token token.Token
 	Value int64
@@ -150,6 +168,41 @@ func (i *IntegerLiteral) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (i *IntegerLiteral) Pos() token.Position { return i.Token.Pos }
+
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (f *FloatLiteral) String() string {
+	return f.Token.Literal
+}
+
+func (f *FloatLiteral) expressionNode() {}
+
+func (f *FloatLiteral) TokenLiteral() string {
+	return f.Token.Literal
+}
+
+func (f *FloatLiteral) Pos() token.Position { return f.Token.Pos }
+
+type NullLiteral struct {
+	Token token.Token
+}
+
+func (n *NullLiteral) String() string {
+	return n.Token.Literal
+}
+
+func (n *NullLiteral) expressionNode() {}
+
+func (n *NullLiteral) TokenLiteral() string {
+	return n.Token.Literal
+}
+
+func (n *NullLiteral) Pos() token.Position { return n.Token.Pos }
+
 type PrefixExpression struct {
 	Token    token.Token
 	Operator string
@@ -166,6 +219,8 @@ func (p *PrefixExpression) expressionNode() {}
 
 func (p *PrefixExpression) TokenLiteral() string { return p.Token.Literal }
 
+func (p *PrefixExpression) Pos() token.Position { return p.Token.Pos }
+
 type InfixExpression struct {
 	Token    token.Token
 	Operator string
@@ -183,6 +238,8 @@ func (i *InfixExpression) expressionNode() {}
 
 func (i *InfixExpression) TokenLiteral() string { return i.Token.Literal }
 
+func (i *InfixExpression) Pos() token.Position { return i.Token.Pos }
+
 type BlockStatement struct {
 	Token      token.Token
 	Statements []Statement
@@ -205,6 +262,8 @@ func (b *BlockStatement) statementNode() {}
 
 func (b *BlockStatement) TokenLiteral() string { return b.Token.Literal }
 
+func (b *BlockStatement) Pos() token.Position { return b.Token.Pos }
+
 type IfExpression struct {
 	Token       token.Token
 	Predicate   Expression
@@ -223,8 +282,6 @@ func (i *IfExpression) String() string {
 		buf.WriteString(i.Alternative.String())
 	}
 
-	buf.WriteString(";")
-
 	return buf.String()
 }
 
@@ -234,9 +291,40 @@ func (i *IfExpression) expressionNode() {}
 
 func (i *IfExpression) TokenLiteral() string { return i.Token.Literal }
 
+func (i *IfExpression) Pos() token.Position { return i.Token.Pos }
+
+// Parameter is a single function parameter. Default is evaluated in the
+// function's environment when the caller omits the argument.
+type Parameter struct {
+	Pattern Pattern
+	Default Expression
+}
+
+func (p *Parameter) String() string {
+	if p.Default != nil {
+		return p.Pattern.String() + " = " + p.Default.String()
+	}
+	return p.Pattern.String()
+}
+
+// ParametersString renders a parameter list, including a variadic rest
+// parameter when present.
+func ParametersString(params []*Parameter, rest *Identifier) string {
+	var out []string
+	for _, p := range params {
+		out = append(out, p.String())
+	}
+	if rest != nil {
+		out = append(out, "..."+rest.String())
+	}
+	return strings.Join(out, ", ")
+}
+
 type FunctionLiteral struct {
 	Token      token.Token
-	Parameters []*Identifier
+	Name       string // Set when the literal is bound directly by a let statement
+	Parameters []*Parameter
+	Rest       *Identifier // Variadic parameter collecting extra arguments
 	Body       *BlockStatement
 }
 
@@ -244,20 +332,31 @@ func (fl *FunctionLiteral) expressionNode() {}
 
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
 
+func (fl *FunctionLiteral) Pos() token.Position { return fl.Token.Pos }
+
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
-	var params []string
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
-	}
 	out.WriteString(fl.TokenLiteral())
 	out.WriteString("(")
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(ParametersString(fl.Parameters, fl.Rest))
 	out.WriteString(")")
 	out.WriteString(fl.Body.String())
 	return out.String()
 }
 
+type SpreadExpression struct {
+	Token token.Token // The '...' token
+	Value Expression
+}
+
+func (se *SpreadExpression) expressionNode() {}
+
+func (se *SpreadExpression) TokenLiteral() string { return se.Token.Literal }
+
+func (se *SpreadExpression) Pos() token.Position { return se.Token.Pos }
+
+func (se *SpreadExpression) String() string { return "..." + se.Value.String() }
+
 type CallExpression struct {
 	Token     token.Token // The '(' token
 	Function  Expression  // Identifier or FunctionLiteral
@@ -268,6 +367,9 @@ func (ce *CallExpression) expressionNode() {}
 
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
 
+// Pos reports the position of the callee rather than of the '(' token.
+func (ce *CallExpression) Pos() token.Position { return ce.Function.Pos() }
+
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 	var args []string
@@ -280,3 +382,389 @@ func (ce *CallExpression) String() string {
 	out.WriteString(")")
 	return out.String()
 }
+
+type StringLiteral struct {
+	Token token.Token
+	Value string
+}
+
+func (s *StringLiteral) String() string {
+	return quote(s.Value)
+}
+
+func (s *StringLiteral) expressionNode() {}
+
+func (s *StringLiteral) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (s *StringLiteral) Pos() token.Position { return s.Token.Pos }
+
+// quote renders a string value back into Monke source form.
+func quote(value string) string {
+	var out strings.Builder
+	out.WriteByte('"')
+	for i := 0; i < len(value); i++ {
+		switch ch := value[i]; ch {
+		case '"', '\\':
+			out.WriteByte('\\')
+			out.WriteByte(ch)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\t':
+			out.WriteString(`\t`)
+		case '\r':
+			out.WriteString(`\r`)
+		default:
+			out.WriteByte(ch)
+		}
+	}
+	out.WriteByte('"')
+	return out.String()
+}
+
+type ArrayLiteral struct {
+	Token    token.Token // The '[' token
+	Elements []Expression
+}
+
+func (al *ArrayLiteral) expressionNode() {}
+
+func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+
+func (al *ArrayLiteral) Pos() token.Position { return al.Token.Pos }
+
+func (al *ArrayLiteral) String() string {
+	var out bytes.Buffer
+	var elements []string
+	for _, el := range al.Elements {
+		elements = append(elements, el.String())
+	}
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+	return out.String()
+}
+
+type HashLiteralPair struct {
+	Key   Expression
+	Value Expression
+}
+
+type HashLiteral struct {
+	Token token.Token // The '{' token
+	Pairs []HashLiteralPair
+}
+
+func (hl *HashLiteral) expressionNode() {}
+
+func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+
+func (hl *HashLiteral) Pos() token.Position { return hl.Token.Pos }
+
+func (hl *HashLiteral) String() string {
+	var out bytes.Buffer
+	var pairs []string
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
+	}
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+	return out.String()
+}
+
+type IndexExpression struct {
+	Token    token.Token // The '[' or '?[' token
+	Left     Expression
+	Index    Expression
+	Optional bool
+}
+
+func (ie *IndexExpression) expressionNode() {}
+
+func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+
+func (ie *IndexExpression) Pos() token.Position { return ie.Token.Pos }
+
+func (ie *IndexExpression) String() string {
+	open := "["
+	if ie.Optional {
+		open = "?["
+	}
+	return fmt.Sprintf("(%s%s%s])", ie.Left.String(), open, ie.Index.String())
+}
+
+type MemberExpression struct {
+	Token    token.Token // The '.' or '?.' token
+	Object   Expression
+	Property *Identifier
+	Optional bool
+}
+
+func (me *MemberExpression) expressionNode() {}
+
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+
+func (me *MemberExpression) Pos() token.Position { return me.Token.Pos }
+
+func (me *MemberExpression) String() string {
+	return fmt.Sprintf("(%s%s%s)", me.Object.String(), me.Token.Literal, me.Property.String())
+}
+
+type ConditionalExpression struct {
+	Token       token.Token // The '?' token
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (ce *ConditionalExpression) expressionNode() {}
+
+func (ce *ConditionalExpression) TokenLiteral() string { return ce.Token.Literal }
+
+func (ce *ConditionalExpression) Pos() token.Position { return ce.Token.Pos }
+
+func (ce *ConditionalExpression) String() string {
+	return fmt.Sprintf("(%s ? %s : %s)", ce.Condition.String(), ce.Consequence.String(), ce.Alternative.String())
+}
+
+type MatchArm struct {
+	Token   token.Token // The '=>' token
+	Pattern Pattern
+	Guard   Expression
+	Body    Expression
+}
+
+func (ma *MatchArm) String() string {
+	if ma.Guard != nil {
+		return fmt.Sprintf("%s if %s => %s", ma.Pattern.String(), ma.Guard.String(), ma.Body.String())
+	}
+	return fmt.Sprintf("%s => %s", ma.Pattern.String(), ma.Body.String())
+}
+
+type MatchExpression struct {
+	Token   token.Token
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+func (me *MatchExpression) expressionNode() {}
+
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+
+func (me *MatchExpression) Pos() token.Position { return me.Token.Pos }
+
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+	var arms []string
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+	out.WriteString("match(")
+	out.WriteString(me.Subject.String())
+	out.WriteString("){ ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+	return out.String()
+}
+
+// Pattern is a binding target: the left-hand side of a let statement, a
+// function parameter or a match arm. An *Identifier binds the matched value
+// to its name, except for the wildcard "_" which binds nothing.
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+func (i *Identifier) patternNode() {}
+
+// IsWildcard reports whether the identifier is the "_" wildcard.
+func (i *Identifier) IsWildcard() bool {
+	return i.Value == "_"
+}
+
+// LiteralPattern matches values equal to a literal.
+type LiteralPattern struct {
+	Token token.Token
+	Value Expression
+}
+
+func (lp *LiteralPattern) patternNode() {}
+
+func (lp *LiteralPattern) TokenLiteral() string { return lp.Token.Literal }
+
+func (lp *LiteralPattern) Pos() token.Position { return lp.Token.Pos }
+
+// String prints a negated literal without the parentheses of a prefix
+// expression, since patterns cannot be parenthesized.
+func (lp *LiteralPattern) String() string {
+	if prefix, ok := lp.Value.(*PrefixExpression); ok {
+		return prefix.Operator + (&LiteralPattern{Value: prefix.Right}).String()
+	}
+	return lp.Value.String()
+}
+
+// ArrayPattern matches arrays element by element. Without a Rest binding
+// the array must have exactly len(Elements) elements.
+type ArrayPattern struct {
+	Token    token.Token // The '[' token
+	Elements []Pattern
+	Rest     *Identifier
+}
+
+func (ap *ArrayPattern) patternNode() {}
+
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+
+func (ap *ArrayPattern) Pos() token.Position { return ap.Token.Pos }
+
+func (ap *ArrayPattern) String() string {
+	var out bytes.Buffer
+	var elements []string
+	for _, el := range ap.Elements {
+		elements = append(elements, el.String())
+	}
+	if ap.Rest != nil {
+		elements = append(elements, ".."+ap.Rest.String())
+	}
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+	return out.String()
+}
+
+type HashPatternPair struct {
+	Key   string
+	Value Pattern
+}
+
+// HashPattern matches hashes that contain every listed string key. Extra
+// keys are ignored.
+type HashPattern struct {
+	Token token.Token // The '{' token
+	Pairs []HashPatternPair
+}
+
+func (hp *HashPattern) patternNode() {}
+
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+
+func (hp *HashPattern) Pos() token.Position { return hp.Token.Pos }
+
+func (hp *HashPattern) String() string {
+	var out bytes.Buffer
+	var pairs []string
+	for _, pair := range hp.Pairs {
+		if ident, ok := pair.Value.(*Identifier); ok && ident.Value == pair.Key {
+			pairs = append(pairs, pair.Key)
+			continue
+		}
+		pairs = append(pairs, quote(pair.Key)+": "+pair.Value.String())
+	}
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+	return out.String()
+}
+
+// NamedArgument is a name: value argument in a call expression.
+type NamedArgument struct {
+	Token token.Token // The argument name token
+	Name  *Identifier
+	Value Expression
+}
+
+func (na *NamedArgument) expressionNode() {}
+
+func (na *NamedArgument) TokenLiteral() string { return na.Token.Literal }
+
+func (na *NamedArgument) Pos() token.Position { return na.Token.Pos }
+
+func (na *NamedArgument) String() string { return na.Name.String() + ": " + na.Value.String() }
+
+type ThrowStatement struct {
+	Token token.Token
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode() {}
+
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+
+func (ts *ThrowStatement) Pos() token.Position { return ts.Token.Pos }
+
+func (ts *ThrowStatement) String() string {
+	return fmt.Sprintf("%s %s;", ts.TokenLiteral(), ts.Value.String())
+}
+
+// TryExpression evaluates Block, running Catch when it fails and Finally in
+// every case. CatchParam is optional; Catch and Finally may each be nil but
+// not both.
+type TryExpression struct {
+	Token      token.Token
+	Block      *BlockStatement
+	CatchParam Pattern
+	Catch      *BlockStatement
+	Finally    *BlockStatement
+}
+
+func (te *TryExpression) expressionNode() {}
+
+func (te *TryExpression) TokenLiteral() string { return te.Token.Literal }
+
+func (te *TryExpression) Pos() token.Position { return te.Token.Pos }
+
+func (te *TryExpression) String() string {
+	buf := strings.Builder{}
+	buf.WriteString("try")
+	buf.WriteString(te.Block.String())
+	if te.Catch != nil {
+		buf.WriteString("catch")
+		if te.CatchParam != nil {
+			buf.WriteString("(")
+			buf.WriteString(te.CatchParam.String())
+			buf.WriteString(")")
+		}
+		buf.WriteString(te.Catch.String())
+	}
+	if te.Finally != nil {
+		buf.WriteString("finally")
+		buf.WriteString(te.Finally.String())
+	}
+	return buf.String()
+}
+
+// ImportStatement evaluates the module at Path and binds it to Alias.
+type ImportStatement struct {
+	Token token.Token
+	Path  *StringLiteral
+	Alias *Identifier
+}
+
+func (is *ImportStatement) statementNode() {}
+
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+
+func (is *ImportStatement) Pos() token.Position { return is.Token.Pos }
+
+func (is *ImportStatement) String() string {
+	return fmt.Sprintf("%s %s as %s;", is.TokenLiteral(), is.Path.String(), is.Alias.String())
+}
+
+// ExportStatement binds like Let and also exposes the bound names to modules
+// importing this one.
+type ExportStatement struct {
+	Token token.Token
+	Let   *LetStatement
+}
+
+func (es *ExportStatement) statementNode() {}
+
+func (es *ExportStatement) TokenLiteral() string { return es.Token.Literal }
+
+func (es *ExportStatement) Pos() token.Position { return es.Token.Pos }
+
+func (es *ExportStatement) String() string {
+	return es.TokenLiteral() + " " + es.Let.String()
+}
@@ -0,0 +1,152 @@
+package evaluator
+
+import "github.com/muter3000/monkeparser/pkg/object"
+
+var builtins = map[string]*object.Builtin{
+	"len": {
+		Name:       "len",
+		Parameters: []string{"value"},
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError(object.ArgumentError, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *object.String:
+				return &object.Integer{Value: int64(len(arg.Value))}
+			case *object.Array:
+				return &object.Integer{Value: int64(len(arg.Elements))}
+			case *object.Hash:
+				return &object.Integer{Value: int64(len(arg.Pairs))}
+			default:
+				return newError(object.TypeError, "argument to `len` not supported, got %s", args[0].Type())
+			}
+		},
+	},
+	"first": {
+		Name:       "first",
+		Parameters: []string{"array"},
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError(object.ArgumentError, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+			array, ok := args[0].(*object.Array)
+			if !ok {
+				return newError(object.TypeError, "argument to `first` must be ARRAY, got %s", args[0].Type())
+			}
+			if len(array.Elements) == 0 {
+				return NULL
+			}
+			return array.Elements[0]
+		},
+	},
+	"last": {
+		Name:       "last",
+		Parameters: []string{"array"},
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError(object.ArgumentError, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+			array, ok := args[0].(*object.Array)
+			if !ok {
+				return newError(object.TypeError, "argument to `last` must be ARRAY, got %s", args[0].Type())
+			}
+			if len(array.Elements) == 0 {
+				return NULL
+			}
+			return array.Elements[len(array.Elements)-1]
+		},
+	},
+	"rest": {
+		Name:       "rest",
+		Parameters: []string{"array"},
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError(object.ArgumentError, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+			array, ok := args[0].(*object.Array)
+			if !ok {
+				return newError(object.TypeError, "argument to `rest` must be ARRAY, got %s", args[0].Type())
+			}
+			if len(array.Elements) == 0 {
+				return NULL
+			}
+			elements := make([]object.Object, len(array.Elements)-1)
+			copy(elements, array.Elements[1:])
+			return &object.Array{Elements: elements}
+		},
+	},
+	"push": {
+		Name:       "push",
+		Parameters: []string{"array", "value"},
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError(object.ArgumentError, "wrong number of arguments. got=%d, want=2", len(args))
+			}
+			array, ok := args[0].(*object.Array)
+			if !ok {
+				return newError(object.TypeError, "argument to `push` must be ARRAY, got %s", args[0].Type())
+			}
+			elements := make([]object.Object, len(array.Elements), len(array.Elements)+1)
+			copy(elements, array.Elements)
+			return &object.Array{Elements: append(elements, args[1])}
+		},
+	},
+	"print":      withEvaluator("print", nil, builtinPrint),
+	"println":    withEvaluator("println", nil, builtinPrintln),
+	"read_line":  withEvaluator("read_line", nil, builtinReadLine),
+	"read_file":  withEvaluator("read_file", []string{"path"}, builtinReadFile),
+	"write_file": withEvaluator("write_file", []string{"path", "content"}, builtinWriteFile),
+	"map":        withEvaluator("map", []string{"array", "func"}, builtinMap),
+	"filter":     withEvaluator("filter", []string{"array", "func"}, builtinFilter),
+	"reduce":     withEvaluator("reduce", []string{"array", "func", "initial"}, builtinReduce),
+	"each":       withEvaluator("each", []string{"array", "func"}, builtinEach),
+	"any":        withEvaluator("any", []string{"array", "func"}, builtinAny),
+	"all":        withEvaluator("all", []string{"array", "func"}, builtinAll),
+	"find":       withEvaluator("find", []string{"array", "func"}, builtinFind),
+	"sort":       withEvaluator("sort", []string{"array", "compare"}, builtinSort),
+	"sort_by":    withEvaluator("sort_by", []string{"array", "key"}, builtinSortBy),
+	"zip":        withEvaluator("zip", []string{"first", "second"}, builtinZip),
+	"enumerate":  nativeEvaluatorFunction("enumerate", []string{"array"}, 1, builtinEnumerate),
+	"flatten":    nativeEvaluatorFunction("flatten", []string{"array"}, 1, builtinFlatten),
+	"unique":     nativeFunction("unique", []string{"array"}, 1, builtinUnique),
+	"group_by":   withEvaluator("group_by", []string{"array", "key"}, builtinGroupBy),
+
+	"type":        nativeFunction("type", []string{"value"}, 1, builtinType),
+	"inspect":     nativeFunction("inspect", []string{"value"}, 1, builtinInspect),
+	"is_callable": nativeFunction("is_callable", []string{"value"}, 1, builtinIsCallable),
+	"arity":       nativeFunction("arity", []string{"func"}, 1, builtinArity),
+	"params":      nativeFunction("params", []string{"func"}, 1, builtinParams),
+	"source":      nativeFunction("source", []string{"func"}, 1, builtinSource),
+	"globals":     withEvaluator("globals", nil, builtinGlobals),
+	"locals":      withEvaluator("locals", nil, builtinLocals),
+}
+
+// evaluatorBuiltin implements a builtin that needs the evaluator calling it,
+// for its input and output or capabilities, or to call functions back.
+type evaluatorBuiltin func(e *Evaluator, self *object.Builtin, args []object.Object) object.Object
+
+// evaluatorBuiltins maps builtins created by withEvaluator to their
+// implementations.
+var evaluatorBuiltins = make(map[*object.Builtin]evaluatorBuiltin)
+
+// withEvaluator creates a builtin that evaluators call as fn. Its own Fn can
+// only report an error, since a host calling it from Go gives no evaluator;
+// hosts call such builtins with Evaluator.ApplyContext instead.
+func withEvaluator(name string, params []string, fn evaluatorBuiltin) *object.Builtin {
+	builtin := &object.Builtin{Name: name, Parameters: params}
+	builtin.Fn = func(args ...object.Object) object.Object {
+		return newError(object.RuntimeError, "builtin %s must be called by an evaluator", name)
+	}
+	evaluatorBuiltins[builtin] = fn
+	return builtin
+}
+
+// DefaultBuiltins returns a copy of the builtins that New installs, for hosts
+// that want to add or remove some.
+func DefaultBuiltins() map[string]*object.Builtin {
+	copied := make(map[string]*object.Builtin, len(builtins))
+	for name, builtin := range builtins {
+		copied[name] = builtin
+	}
+	return copied
+}
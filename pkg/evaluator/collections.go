@@ -0,0 +1,427 @@
+package evaluator
+
+import (
+	"github.com/muter3000/monkeparser/pkg/object"
+	"github.com/muter3000/monkeparser/pkg/token"
+	"sort"
+	"strings"
+)
+
+// The collection builtins that take a function call it back through the
+// evaluator, so errors raised by a callback, including cancellation and
+// limits, end the builtin and propagate to its caller.
+
+func builtinMap(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+	array, fn, err := arrayAndFunctionArgs(self, args)
+	if err != nil {
+		return err
+	}
+	elements := make([]object.Object, len(array.Elements))
+	for i, el := range array.Elements {
+		result := e.callBack(fn, el)
+		if isError(result) {
+			return result
+		}
+		elements[i] = result
+	}
+	return &object.Array{Elements: elements}
+}
+
+func builtinFilter(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+	array, fn, err := arrayAndFunctionArgs(self, args)
+	if err != nil {
+		return err
+	}
+	var elements []object.Object
+	for _, el := range array.Elements {
+		result := e.callBack(fn, el)
+		if isError(result) {
+			return result
+		}
+		if isTruthy(result) {
+			elements = append(elements, el)
+		}
+	}
+	return &object.Array{Elements: elements}
+}
+
+// builtinReduce folds the array from the left with fn(acc, element). Without
+// an initial value the first element is the initial value.
+func builtinReduce(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+	if err := checkArgCount(args, 2, 3); err != nil {
+		return err
+	}
+	array, fn, err := arrayAndFunctionArgs(self, args[:2])
+	if err != nil {
+		return err
+	}
+	elements := array.Elements
+	var acc object.Object
+	if len(args) == 3 {
+		acc = args[2]
+	} else {
+		if len(elements) == 0 {
+			return newError(object.ArgumentError, "%s of an empty array with no initial value", self.Name)
+		}
+		acc, elements = elements[0], elements[1:]
+	}
+	for _, el := range elements {
+		acc = e.callBack(fn, acc, el)
+		if isError(acc) {
+			return acc
+		}
+	}
+	return acc
+}
+
+func builtinEach(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+	array, fn, err := arrayAndFunctionArgs(self, args)
+	if err != nil {
+		return err
+	}
+	for _, el := range array.Elements {
+		if result := e.callBack(fn, el); isError(result) {
+			return result
+		}
+	}
+	return NULL
+}
+
+func builtinAny(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+	return e.search(self, args, true, func(el object.Object) object.Object { return TRUE }, FALSE)
+}
+
+func builtinAll(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+	return e.search(self, args, false, func(el object.Object) object.Object { return FALSE }, TRUE)
+}
+
+func builtinFind(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+	return e.search(self, args, true, func(el object.Object) object.Object { return el }, NULL)
+}
+
+// search calls fn on the elements in order until its result is as truthy as
+// want, and returns found of that element. Without such an element it
+// returns otherwise.
+func (e *Evaluator) search(self *object.Builtin, args []object.Object, want bool, found func(object.Object) object.Object, otherwise object.Object) object.Object {
+	array, fn, err := arrayAndFunctionArgs(self, args)
+	if err != nil {
+		return err
+	}
+	for _, el := range array.Elements {
+		result := e.callBack(fn, el)
+		if isError(result) {
+			return result
+		}
+		if isTruthy(result) == want {
+			return found(el)
+		}
+	}
+	return otherwise
+}
+
+// builtinSort returns a sorted copy of the array. The sort is stable. The
+// optional compare(a, b) returns a negative number when a goes before b, a
+// positive one when it goes after and zero when either order will do.
+// Without it, elements must be all numbers or all strings.
+func builtinSort(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+	if err := checkArgCount(args, 1, 2); err != nil {
+		return err
+	}
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return argTypeError(self, 0, object.ARRAY_OBJ, args[0])
+	}
+	compare := compareValues
+	if len(args) == 2 {
+		fn, err := functionArg(self, args, 1)
+		if err != nil {
+			return err
+		}
+		compare = func(a, b object.Object) (int, *object.Error) {
+			result := e.callBack(fn, a, b)
+			switch result := result.(type) {
+			case *object.Error:
+				return 0, result
+			case *object.Integer, *object.Float:
+				return sign(toFloat(result)), nil
+			default:
+				return 0, newError(object.TypeError, "`%s` of `%s` must return INTEGER or FLOAT, got %s",
+					self.Parameters[1], self.Name, result.Type())
+			}
+		}
+	}
+	elements := make([]object.Object, len(array.Elements))
+	copy(elements, array.Elements)
+	if err := sortStable(elements, elements, compare); err != nil {
+		return err
+	}
+	return &object.Array{Elements: elements}
+}
+
+// builtinSortBy sorts a copy of the array by the keys that fn gives its
+// elements. fn is called once per element and the sort is stable.
+func builtinSortBy(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+	array, fn, err := arrayAndFunctionArgs(self, args)
+	if err != nil {
+		return err
+	}
+	keys := make([]object.Object, len(array.Elements))
+	for i, el := range array.Elements {
+		key := e.callBack(fn, el)
+		if isError(key) {
+			return key
+		}
+		keys[i] = key
+	}
+	elements := make([]object.Object, len(array.Elements))
+	copy(elements, array.Elements)
+	if err := sortStable(keys, elements, compareValues); err != nil {
+		return err
+	}
+	return &object.Array{Elements: elements}
+}
+
+// builtinZip pairs up the elements of its arrays, stopping at the end of the
+// shortest.
+// builtinZip pairs up the elements of the arrays at the same index, stopping
+// at the end of the shortest. It charges for every tuple as it creates it,
+// since only the outer array is tracked when it returns.
+func builtinZip(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+	arrays := make([]*object.Array, len(args))
+	length := -1
+	for i, arg := range args {
+		array, ok := arg.(*object.Array)
+		if !ok {
+			return newError(object.TypeError, "argument %d to `zip` must be ARRAY, got %s", i+1, arg.Type())
+		}
+		arrays[i] = array
+		if length < 0 || len(array.Elements) < length {
+			length = len(array.Elements)
+		}
+	}
+	if length < 0 {
+		length = 0
+	}
+	if err := e.checkAllocation(arraySize + elementSize*int64(length)); err != nil {
+		return err
+	}
+	tuples := make([]object.Object, length)
+	for i := range tuples {
+		tuple := make([]object.Object, len(arrays))
+		for j, array := range arrays {
+			tuple[j] = array.Elements[i]
+		}
+		tuples[i] = e.track(&object.Array{Elements: tuple})
+		if isError(tuples[i]) {
+			return tuples[i]
+		}
+	}
+	return &object.Array{Elements: tuples}
+}
+
+func builtinEnumerate(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return argTypeError(self, 0, object.ARRAY_OBJ, args[0])
+	}
+	if err := e.checkAllocation(arraySize + elementSize*int64(len(array.Elements))); err != nil {
+		return err
+	}
+	pairs := make([]object.Object, len(array.Elements))
+	for i, el := range array.Elements {
+		if err := e.charge(integerSize); err != nil {
+			return err
+		}
+		pairs[i] = e.track(&object.Array{Elements: []object.Object{&object.Integer{Value: int64(i)}, el}})
+		if isError(pairs[i]) {
+			return pairs[i]
+		}
+	}
+	return &object.Array{Elements: pairs}
+}
+
+// builtinFlatten splices the elements of nested arrays into the array, one
+// level deep.
+func builtinFlatten(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return argTypeError(self, 0, object.ARRAY_OBJ, args[0])
+	}
+	length := 0
+	for _, el := range array.Elements {
+		if nested, ok := el.(*object.Array); ok {
+			length += len(nested.Elements)
+		} else {
+			length++
+		}
+	}
+	if err := e.checkAllocation(arraySize + elementSize*int64(length)); err != nil {
+		return err
+	}
+	elements := make([]object.Object, 0, length)
+	for _, el := range array.Elements {
+		if nested, ok := el.(*object.Array); ok {
+			elements = append(elements, nested.Elements...)
+		} else {
+			elements = append(elements, el)
+		}
+	}
+	return &object.Array{Elements: elements}
+}
+
+// builtinUnique keeps the first of equal elements, which must be usable as
+// hash keys.
+func builtinUnique(self *object.Builtin, args []object.Object) object.Object {
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return argTypeError(self, 0, object.ARRAY_OBJ, args[0])
+	}
+	seen := make(map[object.HashKey]bool)
+	var elements []object.Object
+	for _, el := range array.Elements {
+		key, err := hashKey(self, el)
+		if err != nil {
+			return err
+		}
+		if !seen[key] {
+			seen[key] = true
+			elements = append(elements, el)
+		}
+	}
+	return &object.Array{Elements: elements}
+}
+
+// builtinGroupBy collects the elements into a hash of arrays by the keys fn
+// gives them, keeping their order.
+func builtinGroupBy(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+	array, fn, err := arrayAndFunctionArgs(self, args)
+	if err != nil {
+		return err
+	}
+	pairs := make(map[object.HashKey]object.HashPair)
+	for _, el := range array.Elements {
+		key := e.callBack(fn, el)
+		if isError(key) {
+			return key
+		}
+		hk, err := hashKey(self, key)
+		if err != nil {
+			return err
+		}
+		group, ok := pairs[hk]
+		if !ok {
+			group = object.HashPair{Key: key, Value: &object.Array{}}
+		}
+		group.Value.(*object.Array).Elements = append(group.Value.(*object.Array).Elements, el)
+		pairs[hk] = group
+	}
+	return &object.Hash{Pairs: pairs}
+}
+
+// callBack calls fn, a function passed to a builtin, with args.
+func (e *Evaluator) callBack(fn object.Object, args ...object.Object) object.Object {
+	if err := e.checkContext(); err != nil {
+		return err
+	}
+	return e.applyFunction(fn, args, nil, token.Position{})
+}
+
+// functionType names the accepted types in errors for callback arguments.
+const functionType = object.FUNCTION_OBJ + " or " + object.BUILTIN_OBJ
+
+func functionArg(fn *object.Builtin, args []object.Object, i int) (object.Object, *object.Error) {
+	switch args[i].(type) {
+	case *object.Function, *object.Builtin:
+		return args[i], nil
+	default:
+		return nil, argTypeError(fn, i, functionType, args[i])
+	}
+}
+
+func arrayAndFunctionArgs(fn *object.Builtin, args []object.Object) (*object.Array, object.Object, *object.Error) {
+	if err := checkArgCount(args, 2, 2); err != nil {
+		return nil, nil, err
+	}
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return nil, nil, argTypeError(fn, 0, object.ARRAY_OBJ, args[0])
+	}
+	callback, err := functionArg(fn, args, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	return array, callback, nil
+}
+
+func hashKey(fn *object.Builtin, obj object.Object) (object.HashKey, *object.Error) {
+	hashable, ok := obj.(object.Hashable)
+	if !ok {
+		return object.HashKey{}, newError(object.TypeError, "unusable as hash key in call to %s: %s", fn.Name, obj.Type())
+	}
+	return hashable.HashKey(), nil
+}
+
+// compareValues orders numbers by value and strings lexically.
+func compareValues(a, b object.Object) (int, *object.Error) {
+	if isNumber(a) && isNumber(b) {
+		ai, aok := a.(*object.Integer)
+		bi, bok := b.(*object.Integer)
+		if aok && bok {
+			// Compared directly, since large integers lose precision
+			// as floats
+			switch {
+			case ai.Value < bi.Value:
+				return -1, nil
+			case ai.Value > bi.Value:
+				return 1, nil
+			}
+			return 0, nil
+		}
+		return sign(toFloat(a) - toFloat(b)), nil
+	}
+	as, aok := a.(*object.String)
+	bs, bok := b.(*object.String)
+	if aok && bok {
+		return strings.Compare(as.Value, bs.Value), nil
+	}
+	return 0, newError(object.TypeError, "cannot compare %s and %s", a.Type(), b.Type())
+}
+
+// sortStable sorts elements by keys, which may be the same slice. Once
+// compare returns an error, the remaining comparisons are skipped and the
+// error is returned.
+func sortStable(keys, elements []object.Object, compare func(a, b object.Object) (int, *object.Error)) *object.Error {
+	var err *object.Error
+	indexes := make([]int, len(keys))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		if err != nil {
+			return false
+		}
+		var c int
+		c, err = compare(keys[indexes[i]], keys[indexes[j]])
+		return c < 0
+	})
+	if err != nil {
+		return err
+	}
+	sorted := make([]object.Object, len(elements))
+	for i, index := range indexes {
+		sorted[i] = elements[index]
+	}
+	copy(elements, sorted)
+	return nil
+}
+
+func sign(x float64) int {
+	switch {
+	case x < 0:
+		return -1
+	case x > 0:
+		return 1
+	default:
+		return 0
+	}
+}
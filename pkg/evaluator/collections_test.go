@@ -0,0 +1,127 @@
+package evaluator_test
+
+import (
+	"context"
+	"github.com/muter3000/monkeparser/pkg/evaluator"
+	"github.com/muter3000/monkeparser/pkg/lexer"
+	"github.com/muter3000/monkeparser/pkg/object"
+	"github.com/muter3000/monkeparser/pkg/parser"
+	"testing"
+	"time"
+)
+
+func TestCollectionBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`map([1, 2, 3], fn(x) { x * 2 })`, "[2, 4, 6]"},
+		{`map([], fn(x) { x })`, "[]"},
+		{`map(["a", "bc"], len)`, "[1, 2]"},
+		{`map(func: fn(x) { [x] }, array: [1])`, "[[1]]"},
+		{`filter([1, 2, 3, 4], fn(x) { x > 2 })`, "[3, 4]"},
+		{`filter([0, 1, null, "", false], fn(x) { x })`, "[1, ]"},
+		{`reduce([1, 2, 3], fn(acc, x) { acc + x })`, "6"},
+		{`reduce([1, 2, 3], fn(acc, x) { push(acc, x * x) }, [])`, "[1, 4, 9]"},
+		{`reduce([], fn(acc, x) { acc + x }, 0)`, "0"},
+		{`let seen = []; each([1, 2], fn(x) { seen = push(seen, x) })`, "null"},
+		{`any([1, 2, 3], fn(x) { x > 2 })`, "true"},
+		{`any([], fn(x) { true })`, "false"},
+		{`all([1, 2, 3], fn(x) { x > 0 })`, "true"},
+		{`all([1, 2, 3], fn(x) { x > 1 })`, "false"},
+		{`all([], fn(x) { false })`, "true"},
+		{`find([1, 2, 3, 4], fn(x) { x > 2 })`, "3"},
+		{`find([1, 2], fn(x) { x > 2 })`, "null"},
+		{`sort([3, 1.5, 2, -1])`, "[-1, 1.5, 2, 3]"},
+		{`sort(["b", "c", "a"])`, "[a, b, c]"},
+		{`sort([9223372036854775807, 9223372036854775806])`, "[9223372036854775806, 9223372036854775807]"},
+		{`sort([3, 1, 2], fn(a, b) { b - a })`, "[3, 2, 1]"},
+		{`let xs = [2, 1]; sort(xs); xs`, "[2, 1]"},
+		{`sort([[1, "b"], [0, "x"], [1, "a"], [0, "y"]], fn(a, b) { a[0] - b[0] })`, "[[0, x], [0, y], [1, b], [1, a]]"},
+		{`sort_by(["ccc", "a", "bb", "d"], len)`, "[a, d, bb, ccc]"},
+		{`sort_by([{"n": 2}, {"n": 1}], fn(h) { h.n })`, "[{n: 1}, {n: 2}]"},
+		{`zip([1, 2, 3], ["a", "b"])`, "[[1, a], [2, b]]"},
+		{`zip([1], [2], [3])`, "[[1, 2, 3]]"},
+		{`zip()`, "[]"},
+		{`zip(second: ["a"], first: [1])`, "[[1, a]]"},
+		{`enumerate(["a", "b"])`, "[[0, a], [1, b]]"},
+		{`flatten([1, [2, [3]], [], 4])`, "[1, 2, [3], 4]"},
+		{`unique([1, "1", 1, true, "1", 2])`, "[1, 1, true, 2]"},
+		{`let groups = group_by([1, 5, 2, 4, 3], fn(x) { x > 2 }); [groups[false], groups[true]]`, "[[1, 2], [5, 4, 3]]"},
+		{`group_by([], fn(x) { x })`, "{}"},
+		{`unique([1.5, 1.5, 0.0, -0.0, 1, 1.0])`, "[1.5, 0.0, 1, 1.0]"},
+		{`let groups = group_by([1, 2, 3, 4], fn(x) { x / 2.0 > 1 }); groups[true]`, "[3, 4]"},
+		{`let groups = group_by([1, 2, 3], fn(x) { x / 2.0 }); [groups[0.5], groups[1.0], groups[1]]`, "[[1], [2], null]"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. want=%s, got=%+v", tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+func TestCollectionBuiltinErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`map(1, fn(x) { x })`, "argument `array` to `map` must be ARRAY, got INTEGER"},
+		{`map([1], 1)`, "argument `func` to `map` must be FUNCTION or BUILTIN, got INTEGER"},
+		{`filter([1])`, "wrong number of arguments. got=1, want=2"},
+		{`map([1, 2], fn(x) { x + "a" })`, "type mismatch: INTEGER + STRING"},
+		{`map([1], fn(x, y) { x })`, "wrong number of arguments. got=1, want=2"},
+		{`each([1, 2], fn(x) { if (x == 2) { throw "two" } })`, "two"},
+		{`reduce([], fn(acc, x) { acc })`, "reduce of an empty array with no initial value"},
+		{`sort([1, "a"])`, "cannot compare STRING and INTEGER"},
+		{`sort([[1], [2]])`, "cannot compare ARRAY and ARRAY"},
+		{`sort([1, 2], fn(a, b) { a < b })`, "`compare` of `sort` must return INTEGER or FLOAT, got BOOLEAN"},
+		{`sort([1, 2, 3], fn(a, b) { missing })`, "identifier not found: missing"},
+		{`sort_by([1, 2], fn(x) { [x] })`, "cannot compare ARRAY and ARRAY"},
+		{`zip([1], 2)`, "argument 2 to `zip` must be ARRAY, got INTEGER"},
+		{`unique([[1]])`, "unusable as hash key in call to unique: ARRAY"},
+		{`group_by([1], fn(x) { [x] })`, "unusable as hash key in call to group_by: ARRAY"},
+		{`flatten("abc")`, "argument `array` to `flatten` must be ARRAY, got STRING"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("object is not Error for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		}
+	}
+
+	evaluated := testEval(`try { map([1], fn(x) { throw {"code": x} }) } catch (e) { e.code }`)
+	testIntegerObject(t, evaluated, 1)
+}
+
+func TestCollectionBuiltinLimits(t *testing.T) {
+	program := parser.New(lexer.New(`let loop = fn(x) { loop(x) }; map([1], loop)`)).ParseProgram()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	evaluated := evaluator.EvalContext(ctx, program, object.NewEnvironment())
+	testErrorKind(t, evaluated, object.CancelledError)
+
+	program = parser.New(lexer.New(`try { filter([1, 2, 3], fn(x) { let loop = fn() { loop() }; loop() }) } catch (e) { 1 }`)).ParseProgram()
+	e := evaluator.New()
+	e.MaxSteps = 1000
+	evaluated = e.Eval(program, object.NewEnvironment())
+	testErrorKind(t, evaluated, object.LimitError)
+
+	big := &object.Array{Elements: make([]object.Object, 1000)}
+	for i := range big.Elements {
+		big.Elements[i] = evaluator.NULL
+	}
+	for _, input := range []string{`enumerate(big)`, `zip(big, big)`, `flatten([big, big, big, big])`} {
+		env := object.NewEnvironment()
+		env.Set("big", big)
+		e = evaluator.New()
+		e.MaxMemory = 30000
+		evaluated = e.Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+		testErrorKind(t, evaluated, object.LimitError)
+	}
+}
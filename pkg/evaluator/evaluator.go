@@ -1,83 +1,239 @@
 package evaluator
 
 import (
+	"bufio"
+	"context"
 	"fmt"
 	"github.com/muter3000/monkeparser/pkg/ast"
+	"github.com/muter3000/monkeparser/pkg/module"
 	"github.com/muter3000/monkeparser/pkg/object"
 	"github.com/muter3000/monkeparser/pkg/token"
+	"github.com/muter3000/monkeparser/pkg/vfs"
+	"io"
+	"io/fs"
+	"math/rand"
+	"os"
+	"time"
 )
 
 var (
-	NULL  = &object.Null{}
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	NULL  = object.NULL
+	TRUE  = object.TRUE
+	FALSE = object.FALSE
 )
 
+// DefaultMaxDepth is the call depth limit of evaluators created by New.
+const DefaultMaxDepth = 10000
+
+// Evaluator holds the state of one evaluation. It is not safe for concurrent
+// use; give every goroutine its own.
+type Evaluator struct {
+	// MaxDepth is the number of nested Monke function calls allowed before
+	// a call fails with a RecursionError. Tail calls do not nest.
+	MaxDepth int
+	// MaxSteps caps the number of nodes evaluated and MaxMemory
+	// the approximate number of bytes allocated; see Usage. Zero means no
+	// limit. Going over either ends the evaluation with a fatal LimitError.
+	MaxSteps  int64
+	MaxMemory int64
+	// Builtins are the functions available under their names when no
+	// variable shadows them.
+	Builtins map[string]*object.Builtin
+	// Stdout and Stderr receive the output of scripts. Nil discards it.
+	Stdout io.Writer
+	Stderr io.Writer
+	// Stdin is where read_line reads lines from. Nil, the default, denies
+	// reading. Pass the same *bufio.Reader to evaluators that take turns reading, so that
+	// none loses input another has buffered.
+	Stdin io.Reader
+	// FS is the filesystem scripts may read. Nil means no file access.
+	FS fs.FS
+	// WriteFS is the filesystem write_file writes to. Nil denies writes.
+	WriteFS vfs.WriteFS
+	// Loader loads the modules that scripts import. When it is nil,
+	// modules are loaded from FS.
+	Loader module.Loader
+	// Modules caches imported modules by name. Evaluators sharing the map
+	// evaluate each module only once.
+	Modules map[string]*object.Module
+	// Clock tells the time module the current time. Nil means time.Now.
+	Clock func() time.Time
+	// Rand is the source of the random module. Share one between
+	// evaluators to continue its sequence; nil means a new source seeded
+	// from the current time.
+	Rand *rand.Rand
+
+	ctx       context.Context
+	depth     int
+	steps     int64
+	memory    int64
+	module    *moduleState        // The module being evaluated, nil for the main program
+	importing []string            // Names of the modules being evaluated, outermost first
+	lines     *bufio.Reader       // Buffers Stdin for read_line
+	ownRand   *rand.Rand          // Used by the random module when Rand is nil
+	caller    *object.Environment // Where the latest call was made, for globals and locals
+}
+
+func New() *Evaluator {
+	return &Evaluator{
+		MaxDepth: DefaultMaxDepth,
+		Builtins: builtins,
+		Stdout:   os.Stdout,
+		Stderr:   os.Stderr,
+		Modules:  make(map[string]*object.Module),
+		ctx:      context.Background(),
+	}
+}
+
+// Eval evaluates node with a new Evaluator.
 func Eval(node ast.Node, environment *object.Environment) object.Object {
+	return New().Eval(node, environment)
+}
+
+// EvalContext evaluates node with a new Evaluator that stops once ctx is done.
+func EvalContext(ctx context.Context, node ast.Node, environment *object.Environment) object.Object {
+	return New().EvalContext(ctx, node, environment)
+}
+
+// EvalContext evaluates node like Eval, checking ctx before every function
+// call. When ctx is cancelled or its deadline passes, evaluation stops with
+// a fatal CancelledError.
+func (e *Evaluator) EvalContext(ctx context.Context, node ast.Node, environment *object.Environment) object.Object {
+	prev := e.ctx
+	e.ctx = ctx
+	defer func() { e.ctx = prev }()
+	return e.Eval(node, environment)
+}
+
+// ApplyContext calls fn, a Monke function or a builtin, with args from Go.
+// It stops like EvalContext once ctx is done.
+func (e *Evaluator) ApplyContext(ctx context.Context, fn object.Object, args ...object.Object) object.Object {
+	prev := e.ctx
+	e.ctx = ctx
+	defer func() { e.ctx = prev }()
+	return e.applyFunction(fn, args, nil, token.Position{})
+}
+
+// checkContext reports a fatal error if the context of the evaluation is done.
+func (e *Evaluator) checkContext() *object.Error {
+	select {
+	case <-e.ctx.Done():
+		err := newError(object.CancelledError, "evaluation cancelled: %s", e.ctx.Err())
+		err.Fatal = true
+		return err
+	default:
+		return nil
+	}
+}
+
+func (e *Evaluator) Eval(node ast.Node, environment *object.Environment) object.Object {
+	if err := e.step(node); err != nil {
+		err.Pos = node.Pos()
+		return err
+	}
+	result := e.evalNode(node, environment)
+	if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = node.Pos()
+	}
+	return result
+}
+
+func (e *Evaluator) evalNode(node ast.Node, environment *object.Environment) object.Object {
 	switch node := node.(type) {
 	// Statements
 	case *ast.Program:
-		return evalProgram(node, environment)
+		return e.evalProgram(node, environment)
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression, environment)
+		return e.Eval(node.Expression, environment)
 
 	// Expressions
 	case *ast.Identifier:
-		return evalIdentifier(node.Value, environment)
+		return e.evalIdentifier(node.Value, environment)
 
-	case *ast.CallExpression:
-		function := Eval(node.Function, environment)
-		if isError(function) {
-			return function
-		}
-		args := evalExpressions(node.Arguments, environment)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
-		}
-		return applyFunction(function, args)
+	case *ast.CallExpression, *ast.IndexExpression, *ast.MemberExpression:
+		result, _ := e.evalChain(node.(ast.Expression), environment)
+		return result
 
 	case *ast.IntegerLiteral:
-		return &object.Integer{Value: node.Value}
+		return e.track(&object.Integer{Value: node.Value})
+	case *ast.FloatLiteral:
+		return e.track(&object.Float{Value: node.Value})
 	case *ast.BooleanLiteral:
 		return nativeBoolToBooleanObject(node.Value)
+	case *ast.NullLiteral:
+		return NULL
+	case *ast.StringLiteral:
+		return e.track(&object.String{Value: node.Value})
+	case *ast.ArrayLiteral:
+		elements := e.evalExpressions(node.Elements, environment)
+		if len(elements) == 1 && isError(elements[0]) {
+			return elements[0]
+		}
+		return e.track(&object.Array{Elements: elements})
+	case *ast.HashLiteral:
+		return e.track(e.evalHashLiteral(node, environment))
 
 	case *ast.PrefixExpression:
-		right := Eval(node.Right, environment)
+		right := e.Eval(node.Right, environment)
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return e.track(evalPrefixExpression(node.Operator, right))
 	case *ast.InfixExpression:
-		left := Eval(node.Left, environment)
+		left := e.Eval(node.Left, environment)
 		if isError(left) {
 			return left
 		}
-		right := Eval(node.Right, environment)
+		if node.Operator == token.NULLISH {
+			return e.evalNullishExpression(left, node.Right, environment)
+		}
+		right := e.Eval(node.Right, environment)
 		if isError(right) {
 			return right
 		}
-		return evalInfixExpression(node.Operator, left, right)
+		return e.track(evalInfixExpression(node.Operator, left, right))
 
 	case *ast.LetStatement:
-		val := Eval(node.Value, environment)
+		val := e.Eval(node.Value, environment)
 		if isError(val) {
 			return val
 		}
-		environment.Set(node.Name.String(), val)
+		if err := e.bindPattern(node.Name, val, environment); err != nil {
+			return err
+		}
 
 	// Blocks
 	case *ast.BlockStatement:
-		return evalBlockStatement(node, environment)
+		return e.evalBlockStatement(node, environment)
 	case *ast.IfExpression:
-		return evalIfExpression(node, environment)
+		return e.evalIfExpression(node, environment)
+	case *ast.ConditionalExpression:
+		return e.evalConditionalExpression(node, environment)
+	case *ast.MatchExpression:
+		return e.evalMatchExpression(node, environment)
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Environment: environment, Body: body}
+		return e.track(&object.Function{Name: node.Name, Parameters: params, Rest: node.Rest, Environment: environment, Body: body})
+
+	case *ast.TryExpression:
+		return e.evalTryExpression(node, environment)
+	case *ast.ThrowStatement:
+		val := e.Eval(node.Value, environment)
+		if isError(val) {
+			return val
+		}
+		return newThrownError(val)
+
+	// Modules
+	case *ast.ImportStatement:
+		return e.evalImportStatement(node, environment)
+	case *ast.ExportStatement:
+		return e.evalExportStatement(node, environment)
 
 	// Return
 	case *ast.ReturnStatement:
-		val := Eval(node.ReturnValue, environment)
+		val := e.Eval(node.ReturnValue, environment)
 		if isError(val) {
 			return val
 		}
@@ -86,14 +242,586 @@ func Eval(node ast.Node, environment *object.Environment) object.Object {
 	return nil
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
-	function, ok := fn.(*object.Function)
+// evalTryExpression runs the try block, hands a failure to the catch block
+// and then always runs the finally block. A return or error from the finally
+// block replaces the outcome of the other two. Fatal errors skip both catch
+// and finally.
+func (e *Evaluator) evalTryExpression(te *ast.TryExpression, environment *object.Environment) object.Object {
+	result := e.Eval(te.Block, environment)
+	if err, ok := result.(*object.Error); ok && err.Fatal {
+		return err
+	}
+	if err, ok := result.(*object.Error); ok && te.Catch != nil {
+		catchEnv, envErr := e.newEnclosedEnvironment(environment)
+		if envErr != nil {
+			return envErr
+		}
+		if te.CatchParam != nil {
+			if bindErr := e.bindPattern(te.CatchParam, caughtValue(err), catchEnv); bindErr != nil {
+				return bindErr
+			}
+		}
+		result = e.Eval(te.Catch, catchEnv)
+	}
+	if te.Finally != nil {
+		finally := e.Eval(te.Finally, environment)
+		if finally != nil {
+			if ft := finally.Type(); ft == object.RETURN_VALUE_OBJ || ft == object.ERROR_OBJ {
+				return finally
+			}
+		}
+	}
+	return result
+}
+
+// newThrownError wraps a value raised by throw. Throwing a caught error hash
+// raises it again with its original message and kind.
+func newThrownError(val object.Object) *object.Error {
+	err := &object.Error{Kind: object.ThrownError, Message: val.Inspect(), Value: val}
+	if hash, ok := val.(*object.Hash); ok {
+		if message, ok := hash.Get("message"); ok && message.Type() == object.STRING_OBJ {
+			err.Message = message.(*object.String).Value
+		}
+		if kind, ok := hash.Get("kind"); ok && kind.Type() == object.STRING_OBJ {
+			err.Kind = kind.(*object.String).Value
+		}
+	}
+	return err
+}
+
+// caughtValue is what a catch clause binds: the thrown value itself, or for
+// runtime errors a hash with the message, kind and stack of the error.
+func caughtValue(err *object.Error) object.Object {
+	if err.Value != nil {
+		return err.Value
+	}
+	var stack []object.Object
+	pos := err.Pos
+	for _, frame := range err.Stack {
+		stack = append(stack, newHash(map[string]object.Object{
+			"function": &object.String{Value: frame.Function},
+			"line":     &object.Integer{Value: int64(pos.Line)},
+			"column":   &object.Integer{Value: int64(pos.Column)},
+		}))
+		pos = frame.CallSite
+	}
+	return newHash(map[string]object.Object{
+		"message": &object.String{Value: err.Message},
+		"kind":    &object.String{Value: err.Kind},
+		"line":    &object.Integer{Value: int64(err.Pos.Line)},
+		"column":  &object.Integer{Value: int64(err.Pos.Column)},
+		"stack":   &object.Array{Elements: append([]object.Object{}, stack...)},
+	})
+}
+
+func newHash(values map[string]object.Object) *object.Hash {
+	pairs := make(map[object.HashKey]object.HashPair, len(values))
+	for k, v := range values {
+		key := &object.String{Value: k}
+		pairs[key.HashKey()] = object.HashPair{Key: key, Value: v}
+	}
+	return &object.Hash{Pairs: pairs}
+}
+
+// evalChain evaluates a call, index or member expression. The boolean result
+// reports whether an optional link (?. or ?[) met null, in which case the rest
+// of the chain is skipped and the whole chain evaluates to null.
+func (e *Evaluator) evalChain(node ast.Expression, environment *object.Environment) (object.Object, bool) {
+	switch node := node.(type) {
+	case *ast.CallExpression:
+		function, skipped := e.evalChain(node.Function, environment)
+		if skipped || isError(function) {
+			return function, skipped
+		}
+		args, named, err := e.evalArguments(node.Arguments, environment)
+		if err != nil {
+			return err, false
+		}
+		e.caller = environment
+		return e.applyFunction(function, args, named, node.Pos()), false
+
+	case *ast.IndexExpression:
+		left, skipped := e.evalChain(node.Left, environment)
+		if skipped || isError(left) {
+			return left, skipped
+		}
+		if node.Optional && left == NULL {
+			return NULL, true
+		}
+		index := e.Eval(node.Index, environment)
+		if isError(index) {
+			return index, false
+		}
+		return evalIndexExpression(left, index), false
+
+	case *ast.MemberExpression:
+		obj, skipped := e.evalChain(node.Object, environment)
+		if skipped || isError(obj) {
+			return obj, skipped
+		}
+		if node.Optional && obj == NULL {
+			return NULL, true
+		}
+		return evalMemberExpression(obj, node.Property.Value), false
+
+	default:
+		return e.Eval(node, environment), false
+	}
+}
+
+// tailCall is a call in tail position that has been evaluated up to, but not
+// including, running the callee. applyFunction runs it in place of the frame
+// that made it.
+type tailCall struct {
+	function *object.Function
+	args     []object.Object
+	named    []namedArgument
+	callSite token.Position
+}
+
+func (tc *tailCall) Type() object.ObjectType { return tailCallObj }
+func (tc *tailCall) Inspect() string         { return "tail call " + functionName(tc.function) }
+
+const tailCallObj = "TAIL_CALL"
+
+// evalTail evaluates a node of a function body. When tail is true the value
+// of node is the result of the function, and a call to a Monke function
+// there is returned as a tailCall instead of being made. Return statements
+// are always in tail position. Only blocks, if, ?: and match are looked
+// into; everything else, try in particular, is evaluated with Eval.
+func (e *Evaluator) evalTail(node ast.Node, environment *object.Environment, tail bool) object.Object {
+	if err := e.step(node); err != nil {
+		err.Pos = node.Pos()
+		return err
+	}
+	result := e.evalTailNode(node, environment, tail)
+	if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = node.Pos()
+	}
+	return result
+}
+
+func (e *Evaluator) evalTailNode(node ast.Node, environment *object.Environment, tail bool) object.Object {
+	switch node := node.(type) {
+	case *ast.BlockStatement:
+		var result object.Object
+		for i, statement := range node.Statements {
+			result = e.evalTail(statement, environment, tail && i == len(node.Statements)-1)
+			if result != nil {
+				rt := result.Type()
+				if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+					return result
+				}
+			}
+		}
+		return result
+	case *ast.ExpressionStatement:
+		return e.evalTail(node.Expression, environment, tail)
+	case *ast.ReturnStatement:
+		val := e.evalTail(node.ReturnValue, environment, true)
+		if isError(val) {
+			return val
+		}
+		return &object.ReturnValue{Value: val}
+
+	case *ast.IfExpression:
+		pred := e.Eval(node.Predicate, environment)
+		if isError(pred) {
+			return pred
+		}
+		if pred == NULL {
+			return NULL
+		}
+		if isTruthy(pred) {
+			return e.evalTail(node.Consequence, environment, tail)
+		}
+		if node.Alternative == nil {
+			return NULL
+		}
+		return e.evalTail(node.Alternative, environment, tail)
+	case *ast.ConditionalExpression:
+		condition := e.Eval(node.Condition, environment)
+		if isError(condition) {
+			return condition
+		}
+		if isTruthy(condition) {
+			return e.evalTail(node.Consequence, environment, tail)
+		}
+		return e.evalTail(node.Alternative, environment, tail)
+	case *ast.MatchExpression:
+		arm, armEnv, err := e.selectMatchArm(node, environment)
+		if err != nil {
+			return err
+		}
+		return e.evalTail(arm.Body, armEnv, tail)
+
+	case *ast.CallExpression:
+		if !tail {
+			return e.evalNode(node, environment)
+		}
+		function, skipped := e.evalChain(node.Function, environment)
+		if skipped || isError(function) {
+			return function
+		}
+		args, named, err := e.evalArguments(node.Arguments, environment)
+		if err != nil {
+			return err
+		}
+		if fn, ok := function.(*object.Function); ok {
+			return &tailCall{function: fn, args: args, named: named, callSite: node.Pos()}
+		}
+		e.caller = environment
+		return e.applyFunction(function, args, named, node.Pos())
+
+	default:
+		return e.evalNode(node, environment)
+	}
+}
+
+func evalIndexExpression(left object.Object, index object.Object) object.Object {
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		elements := left.(*object.Array).Elements
+		idx := index.(*object.Integer).Value
+		if idx < 0 || idx >= int64(len(elements)) {
+			return NULL
+		}
+		return elements[idx]
+	case left.Type() == object.HASH_OBJ:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError(object.TypeError, "unusable as hash key: %s", index.Type())
+		}
+		pair, ok := left.(*object.Hash).Pairs[key.HashKey()]
+		if !ok {
+			return NULL
+		}
+		return pair.Value
+	default:
+		return newError(object.TypeError, "index operator not supported: %s", left.Type())
+	}
+}
+
+func evalMemberExpression(obj object.Object, name string) object.Object {
+	if mod, ok := obj.(*object.Module); ok {
+		value, ok := mod.Exports[name]
+		if !ok {
+			return newError(object.NameError, "module %s has no export %s", mod.Name, name)
+		}
+		return value
+	}
+	hash, ok := obj.(*object.Hash)
+	if !ok {
+		return newError(object.TypeError, "member access not supported: %s.%s", obj.Type(), name)
+	}
+	value, ok := hash.Get(name)
 	if !ok {
-		return newError("not a function: %s", fn.Type())
+		return NULL
+	}
+	return value
+}
+
+func (e *Evaluator) evalHashLiteral(node *ast.HashLiteral, environment *object.Environment) object.Object {
+	pairs := make(map[object.HashKey]object.HashPair)
+	for _, pair := range node.Pairs {
+		key := e.Eval(pair.Key, environment)
+		if isError(key) {
+			return key
+		}
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return newError(object.TypeError, "unusable as hash key: %s", key.Type())
+		}
+		value := e.Eval(pair.Value, environment)
+		if isError(value) {
+			return value
+		}
+		pairs[hashKey.HashKey()] = object.HashPair{Key: key, Value: value}
+	}
+	return &object.Hash{Pairs: pairs}
+}
+
+func (e *Evaluator) evalConditionalExpression(ce *ast.ConditionalExpression, environment *object.Environment) object.Object {
+	condition := e.Eval(ce.Condition, environment)
+	if isError(condition) {
+		return condition
+	}
+	if isTruthy(condition) {
+		return e.Eval(ce.Consequence, environment)
+	}
+	return e.Eval(ce.Alternative, environment)
+}
+
+func (e *Evaluator) evalMatchExpression(me *ast.MatchExpression, environment *object.Environment) object.Object {
+	arm, armEnv, err := e.selectMatchArm(me, environment)
+	if err != nil {
+		return err
+	}
+	return e.Eval(arm.Body, armEnv)
+}
+
+// selectMatchArm finds the first arm whose pattern and guard accept the
+// subject and returns it together with the environment holding its bindings.
+func (e *Evaluator) selectMatchArm(me *ast.MatchExpression, environment *object.Environment) (*ast.MatchArm, *object.Environment, object.Object) {
+	subject := e.Eval(me.Subject, environment)
+	if isError(subject) {
+		return nil, nil, subject
+	}
+	for _, arm := range me.Arms {
+		armEnv, err := e.newEnclosedEnvironment(environment)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := e.bindPattern(arm.Pattern, subject, armEnv); err != nil {
+			// Only a mismatch moves on to the next arm. Other errors, such
+			// as running out of memory while binding, end the match.
+			if err.Kind == object.MatchError {
+				continue
+			}
+			return nil, nil, err
+		}
+		if arm.Guard != nil {
+			guard := e.Eval(arm.Guard, armEnv)
+			if isError(guard) {
+				return nil, nil, guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+		return arm, armEnv, nil
+	}
+	return nil, nil, newError(object.MatchError, "no match arm for value: %s", subject.Inspect())
+}
+
+// bindPattern matches value against pattern, binding names into environment
+// as it goes. It returns an error describing the first mismatch, or nil when
+// the value fits the pattern.
+func (e *Evaluator) bindPattern(pattern ast.Pattern, value object.Object, environment *object.Environment) *object.Error {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.IsWildcard() {
+			return nil
+		}
+		if environment.Frozen() {
+			return newError(object.RuntimeError, "cannot bind %s in a frozen environment", pattern.Value)
+		}
+		if err := e.charge(bindingSize); err != nil {
+			return err
+		}
+		environment.Set(pattern.Value, value)
+		return nil
+
+	case *ast.LiteralPattern:
+		expected := e.Eval(pattern.Value, environment)
+		if err, ok := expected.(*object.Error); ok {
+			return err
+		}
+		if !objectsEqual(expected, value) {
+			return newError(object.MatchError, "value %s does not match %s", value.Inspect(), pattern.String())
+		}
+		return nil
+
+	case *ast.ArrayPattern:
+		array, ok := value.(*object.Array)
+		if !ok {
+			return newError(object.MatchError, "cannot match %s against array pattern %s", value.Type(), pattern.String())
+		}
+		if pattern.Rest == nil && len(array.Elements) != len(pattern.Elements) {
+			return newError(object.MatchError, "array pattern %s expects %d elements, got %d",
+				pattern.String(), len(pattern.Elements), len(array.Elements))
+		}
+		if len(array.Elements) < len(pattern.Elements) {
+			return newError(object.MatchError, "array pattern %s expects at least %d elements, got %d",
+				pattern.String(), len(pattern.Elements), len(array.Elements))
+		}
+		for i, el := range pattern.Elements {
+			if err := e.bindPattern(el, array.Elements[i], environment); err != nil {
+				return err
+			}
+		}
+		if pattern.Rest != nil {
+			rest := make([]object.Object, len(array.Elements)-len(pattern.Elements))
+			copy(rest, array.Elements[len(pattern.Elements):])
+			array := &object.Array{Elements: rest}
+			if err := e.charge(objectSize(array)); err != nil {
+				return err
+			}
+			return e.bindPattern(pattern.Rest, array, environment)
+		}
+		return nil
+
+	case *ast.HashPattern:
+		hash, ok := value.(*object.Hash)
+		if !ok {
+			return newError(object.MatchError, "cannot match %s against hash pattern %s", value.Type(), pattern.String())
+		}
+		for _, pair := range pattern.Pairs {
+			v, ok := hash.Get(pair.Key)
+			if !ok {
+				return newError(object.MatchError, "key %q not found for hash pattern %s", pair.Key, pattern.String())
+			}
+			if err := e.bindPattern(pair.Value, v, environment); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return newError(object.MatchError, "unknown pattern: %T", pattern)
 	}
-	extendedEnv := extendFunctionEnv(function, args)
-	evaluated := Eval(function.Body, extendedEnv)
-	return unwrapReturnValue(evaluated)
+}
+
+// objectsEqual compares scalar values the same way == does, so an integer
+// and a float are equal when their values are.
+func objectsEqual(left object.Object, right object.Object) bool {
+	if left.Type() != right.Type() {
+		if isNumber(left) && isNumber(right) {
+			return toFloat(left) == toFloat(right)
+		}
+		return false
+	}
+	switch left := left.(type) {
+	case *object.Integer:
+		return left.Value == right.(*object.Integer).Value
+	case *object.Float:
+		return left.Value == right.(*object.Float).Value
+	case *object.String:
+		return left.Value == right.(*object.String).Value
+	default:
+		return left == right
+	}
+}
+
+// namedArgument is an evaluated name: value call argument.
+type namedArgument struct {
+	name  string
+	value object.Object
+}
+
+// maxTailFrames bounds how many distinct frames of elided tail calls are kept
+// for tracebacks, so that a long chain of tail calls runs in constant space.
+const maxTailFrames = 64
+
+// applyFunction calls fn from the given call site. Errors raised while a
+// Monke function body runs get a stack frame for it on their way out;
+// builtins have no source position of their own and add no frame.
+//
+// Calls in tail position of the body come back as a tailCall and are run by
+// the loop here instead of nesting another Eval, so tail recursion does not
+// grow the Go stack. Their frames are still reported, except that repeats of
+// the same frame are collapsed and only the most recent maxTailFrames are
+// kept.
+func (e *Evaluator) applyFunction(fn object.Object, args []object.Object, named []namedArgument, callSite token.Position) object.Object {
+	switch function := fn.(type) {
+	case *object.Function:
+		if e.depth >= e.MaxDepth {
+			return newError(object.RecursionError, "maximum recursion depth exceeded")
+		}
+		e.depth++
+		defer func() { e.depth-- }()
+		frames := []object.Frame{{Function: functionName(function), CallSite: callSite}}
+		for {
+			if err := e.checkContext(); err != nil {
+				appendFrames(err, frames[:len(frames)-1])
+				return err
+			}
+			extendedEnv, err := e.extendFunctionEnv(function, args, named)
+			if err != nil {
+				if len(frames) > 1 {
+					if !err.Pos.IsValid() {
+						err.Pos = callSite
+					}
+					appendFrames(err, frames[:len(frames)-1])
+				}
+				return err
+			}
+			result := unwrapReturnValue(e.evalTail(function.Body, extendedEnv, true))
+			call, ok := result.(*tailCall)
+			if !ok {
+				if err, ok := result.(*object.Error); ok {
+					appendFrames(err, frames)
+				}
+				return result
+			}
+			function, args, named, callSite = call.function, call.args, call.named, call.callSite
+			frame := object.Frame{Function: functionName(function), CallSite: callSite}
+			if frames[len(frames)-1] != frame {
+				frames = append(frames, frame)
+			}
+			if len(frames) > 2*maxTailFrames {
+				frames = append(frames[:1], frames[len(frames)-maxTailFrames+1:]...)
+			}
+		}
+	case *object.Builtin:
+		args, err := builtinArguments(function, args, named)
+		if err != nil {
+			return err
+		}
+		if fn, ok := evaluatorBuiltins[function]; ok {
+			return e.track(fn(e, function, args))
+		}
+		return e.track(function.Fn(args...))
+	default:
+		return newError(object.TypeError, "not a function: %s", fn.Type())
+	}
+}
+
+// appendFrames adds the frames of a chain of tail calls, given outermost
+// first, to the stack of err.
+func appendFrames(err *object.Error, frames []object.Frame) {
+	for i := len(frames) - 1; i >= 0; i-- {
+		err.PushFrame(frames[i])
+	}
+}
+
+func functionName(fn *object.Function) string {
+	if fn.Name == "" {
+		return "<anonymous>"
+	}
+	return fn.Name
+}
+
+// builtinArguments places named arguments at the positions of the matching
+// entries in the builtin's declared Parameters.
+func builtinArguments(fn *object.Builtin, args []object.Object, named []namedArgument) ([]object.Object, *object.Error) {
+	if len(named) == 0 {
+		return args, nil
+	}
+	if len(fn.Parameters) == 0 {
+		return nil, newError(object.ArgumentError, "builtin %s does not accept named arguments", fn.Name)
+	}
+	slots := make([]object.Object, len(fn.Parameters))
+	copy(slots, args)
+	for _, arg := range named {
+		idx := indexOfName(fn.Parameters, arg.name)
+		if idx < 0 {
+			return nil, newError(object.ArgumentError, "unknown parameter name in call to %s: %s", fn.Name, arg.name)
+		}
+		if slots[idx] != nil {
+			return nil, newError(object.ArgumentError, "duplicate argument for parameter %s", arg.name)
+		}
+		slots[idx] = arg.value
+	}
+	last := len(slots)
+	for last > 0 && slots[last-1] == nil {
+		last--
+	}
+	for i, slot := range slots[:last] {
+		if slot == nil {
+			return nil, newError(object.ArgumentError, "missing argument for parameter %s", fn.Parameters[i])
+		}
+	}
+	return slots[:last], nil
+}
+
+func indexOfName(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
@@ -103,24 +831,105 @@ func unwrapReturnValue(obj object.Object) object.Object {
 	return obj
 }
 
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
-	env := object.NewEnclosedEnvironment(fn.Environment)
+func (e *Evaluator) extendFunctionEnv(fn *object.Function, args []object.Object, named []namedArgument) (*object.Environment, *object.Error) {
+	if len(named) == 0 || len(args) > len(fn.Parameters) {
+		if err := checkArity(fn, len(args)+len(named)); err != nil {
+			return nil, err
+		}
+	}
+	slots := make([]object.Object, len(fn.Parameters))
+	copy(slots, args)
+	for _, arg := range named {
+		idx := parameterIndex(fn, arg.name)
+		if idx < 0 {
+			return nil, newError(object.ArgumentError, "unknown parameter name: %s", arg.name)
+		}
+		if slots[idx] != nil {
+			return nil, newError(object.ArgumentError, "duplicate argument for parameter %s", arg.name)
+		}
+		slots[idx] = arg.value
+	}
+
+	env, err := e.newEnclosedEnvironment(fn.Environment)
+	if err != nil {
+		return nil, err
+	}
 	for paramIdx, param := range fn.Parameters {
-		env.Set(param.Value, args[paramIdx])
+		arg := slots[paramIdx]
+		if arg == nil {
+			if param.Default == nil {
+				return nil, newError(object.ArgumentError, "missing argument for parameter %s", param.Pattern.String())
+			}
+			arg = e.Eval(param.Default, env)
+			if err, ok := arg.(*object.Error); ok {
+				return nil, err
+			}
+		}
+		if err := e.bindPattern(param.Pattern, arg, env); err != nil {
+			return nil, err
+		}
+	}
+	if fn.Rest != nil {
+		rest := []object.Object{}
+		if len(args) > len(fn.Parameters) {
+			rest = append(rest, args[len(fn.Parameters):]...)
+		}
+		array := &object.Array{Elements: rest}
+		if err := e.charge(objectSize(array)); err != nil {
+			return nil, err
+		}
+		if err := e.bindPattern(fn.Rest, array, env); err != nil {
+			return nil, err
+		}
 	}
-	return env
+	return env, nil
 }
 
-func evalIdentifier(value string, environment *object.Environment) object.Object {
-	val, exists := environment.Get(value)
-	if !exists {
-		return newError("identifier not found: %s", value)
+// parameterIndex finds the parameter that a named argument refers to. Only
+// parameters bound to a plain identifier can be passed by name.
+func parameterIndex(fn *object.Function, name string) int {
+	for i, param := range fn.Parameters {
+		if ident, ok := param.Pattern.(*ast.Identifier); ok && ident.Value == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkArity reports a call with fewer arguments than fn has required
+// parameters, or with more than it accepts when fn is not variadic.
+func checkArity(fn *object.Function, got int) *object.Error {
+	required := 0
+	for _, param := range fn.Parameters {
+		if param.Default == nil {
+			required++
+		}
+	}
+	if got >= required && (fn.Rest != nil || got <= len(fn.Parameters)) {
+		return nil
+	}
+	want := fmt.Sprintf("%d", required)
+	switch {
+	case fn.Rest != nil:
+		want = fmt.Sprintf("%d+", required)
+	case required != len(fn.Parameters):
+		want = fmt.Sprintf("%d..%d", required, len(fn.Parameters))
+	}
+	return newError(object.ArgumentError, "wrong number of arguments. got=%d, want=%s", got, want)
+}
+
+func (e *Evaluator) evalIdentifier(value string, environment *object.Environment) object.Object {
+	if val, exists := environment.Get(value); exists {
+		return val
+	}
+	if builtin, exists := e.Builtins[value]; exists {
+		return builtin
 	}
-	return val
+	return newError(object.NameError, "identifier not found: %s", value)
 }
 
-func evalIfExpression(ie *ast.IfExpression, environment *object.Environment) object.Object {
-	pred := Eval(ie.Predicate, environment)
+func (e *Evaluator) evalIfExpression(ie *ast.IfExpression, environment *object.Environment) object.Object {
+	pred := e.Eval(ie.Predicate, environment)
 	if isError(pred) {
 		return pred
 	}
@@ -129,12 +938,12 @@ func evalIfExpression(ie *ast.IfExpression, environment *object.Environment) obj
 	}
 
 	if isTruthy(pred) {
-		return Eval(ie.Consequence, environment)
+		return e.Eval(ie.Consequence, environment)
 	}
 	if ie.Alternative == nil {
 		return NULL
 	}
-	return Eval(ie.Alternative, environment)
+	return e.Eval(ie.Alternative, environment)
 }
 
 func isTruthy(pred object.Object) bool {
@@ -146,24 +955,67 @@ func isTruthy(pred object.Object) bool {
 	case NULL:
 		return false
 	default:
-		if pred.Type() == object.INTEGER_OBJ {
-			return pred.(*object.Integer).Value != 0
+		switch pred := pred.(type) {
+		case *object.Integer:
+			return pred.Value != 0
+		case *object.Float:
+			return pred.Value != 0
 		}
 		return true
 	}
 }
 
+func (e *Evaluator) evalNullishExpression(left object.Object, right ast.Expression, environment *object.Environment) object.Object {
+	if left != NULL {
+		return left
+	}
+	return e.Eval(right, environment)
+}
+
 func evalInfixExpression(operator string, left object.Object, right object.Object) object.Object {
 	switch {
+	case left == NULL || right == NULL:
+		return evalNullInfixExpression(operator, left, right)
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfixExpression(operator, left, right)
 	case left.Type() == object.BOOLEAN_OBJ && right.Type() == object.BOOLEAN_OBJ:
 		return evalBooleanInfixExpression(operator, left, right)
+	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
+		return evalStringInfixExpression(operator, left, right)
 	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s",
+		return newError(object.TypeError, "type mismatch: %s %s %s",
 			left.Type(), operator, right.Type())
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newError(object.TypeError, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func evalNullInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+	switch operator {
+	case token.EQ:
+		return nativeBoolToBooleanObject(left == right)
+	case token.NOT_EQ:
+		return nativeBoolToBooleanObject(left != right)
+	default:
+		return newError(object.TypeError, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func evalStringInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+	lValue := left.(*object.String).Value
+	rValue := right.(*object.String).Value
+
+	switch operator {
+	case token.PLUS:
+		return &object.String{Value: lValue + rValue}
+	case token.EQ:
+		return nativeBoolToBooleanObject(lValue == rValue)
+	case token.NOT_EQ:
+		return nativeBoolToBooleanObject(lValue != rValue)
+	default:
+		return newError(object.TypeError, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
@@ -177,7 +1029,7 @@ func evalBooleanInfixExpression(operator string, left object.Object, right objec
 	case token.NOT_EQ:
 		return nativeBoolToBooleanObject(lValue != rValue)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newError(object.TypeError, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
@@ -207,13 +1059,63 @@ func evalIntegerInfixExpression(operator string, left object.Object, right objec
 	case token.MUL:
 		return &object.Integer{Value: lValue * rValue}
 	case token.DIV:
+		if rValue == 0 {
+			return newError(object.RuntimeError, "division by zero")
+		}
 		return &object.Integer{Value: lValue / rValue}
 	default:
-		return newError("unknown operator: %s %s %s",
+		return newError(object.TypeError, "unknown operator: %s %s %s",
 			left.Type(), operator, right.Type())
 	}
 }
 
+// evalFloatInfixExpression evaluates arithmetic on floats, and on an integer
+// and a float, which is converted to a float first.
+func evalFloatInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+	lValue := toFloat(left)
+	rValue := toFloat(right)
+	switch operator {
+	case token.EQ:
+		return nativeBoolToBooleanObject(lValue == rValue)
+	case token.NOT_EQ:
+		return nativeBoolToBooleanObject(lValue != rValue)
+	case token.LT:
+		return nativeBoolToBooleanObject(lValue < rValue)
+	case token.GT:
+		return nativeBoolToBooleanObject(lValue > rValue)
+	case token.LTE:
+		return nativeBoolToBooleanObject(lValue <= rValue)
+	case token.GTE:
+		return nativeBoolToBooleanObject(lValue >= rValue)
+
+	case token.PLUS:
+		return &object.Float{Value: lValue + rValue}
+	case token.SUB:
+		return &object.Float{Value: lValue - rValue}
+	case token.MUL:
+		return &object.Float{Value: lValue * rValue}
+	case token.DIV:
+		if rValue == 0 {
+			return newError(object.RuntimeError, "division by zero")
+		}
+		return &object.Float{Value: lValue / rValue}
+	default:
+		return newError(object.TypeError, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
+// toFloat converts a number to a float.
+func toFloat(obj object.Object) float64 {
+	if integer, ok := obj.(*object.Integer); ok {
+		return float64(integer.Value)
+	}
+	return obj.(*object.Float).Value
+}
+
 func evalPrefixExpression(operator string, right object.Object) object.Object {
 	switch operator {
 	case token.BANG:
@@ -221,17 +1123,19 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 	case token.SUB:
 		return evalSubOperatorExpression(right)
 	default:
-		return newError("unknown operator: %s%s", operator, right.Type())
+		return newError(object.TypeError, "unknown operator: %s%s", operator, right.Type())
 	}
 }
 
 func evalSubOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
-		return newError("unknown operator: -%s", right.Type())
+	switch right := right.(type) {
+	case *object.Integer:
+		return &object.Integer{Value: -right.Value}
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
+		return newError(object.TypeError, "unknown operator: -%s", right.Type())
 	}
-
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
 }
 
 func evalBangOperatorExpression(right object.Object) object.Object {
@@ -243,13 +1147,7 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 	case NULL:
 		return TRUE
 	default:
-		if right.Type() == object.INTEGER_OBJ {
-			if right.(*object.Integer).Value != 0 {
-				return FALSE
-			}
-			return TRUE
-		}
-		return FALSE
+		return nativeBoolToBooleanObject(!isTruthy(right))
 	}
 }
 
@@ -260,10 +1158,10 @@ func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	return FALSE
 }
 
-func evalBlockStatement(block *ast.BlockStatement, environment *object.Environment) object.Object {
+func (e *Evaluator) evalBlockStatement(block *ast.BlockStatement, environment *object.Environment) object.Object {
 	var result object.Object
 	for _, statement := range block.Statements {
-		result = Eval(statement, environment)
+		result = e.Eval(statement, environment)
 		if result != nil {
 			rt := result.Type()
 			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
@@ -273,10 +1171,10 @@ func evalBlockStatement(block *ast.BlockStatement, environment *object.Environme
 	}
 	return result
 }
-func evalProgram(program *ast.Program, environment *object.Environment) object.Object {
+func (e *Evaluator) evalProgram(program *ast.Program, environment *object.Environment) object.Object {
 	var result object.Object
 	for _, statement := range program.Statements {
-		result = Eval(statement, environment)
+		result = e.Eval(statement, environment)
 		switch r := result.(type) {
 		case *object.ReturnValue:
 			return r.Value
@@ -287,8 +1185,8 @@ func evalProgram(program *ast.Program, environment *object.Environment) object.O
 	return result
 }
 
-func newError(format string, a ...interface{}) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, a...)}
+func newError(kind string, format string, a ...interface{}) *object.Error {
+	return object.NewError(kind, format, a...)
 }
 
 func isError(obj object.Object) bool {
@@ -298,13 +1196,13 @@ func isError(obj object.Object) bool {
 	return false
 }
 
-func evalExpressions(
+func (e *Evaluator) evalExpressions(
 	exps []ast.Expression,
 	env *object.Environment,
 ) []object.Object {
 	var result []object.Object
-	for _, e := range exps {
-		evaluated := Eval(e, env)
+	for _, exp := range exps {
+		evaluated := e.Eval(exp, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
 		}
@@ -312,3 +1210,41 @@ func evalExpressions(
 	}
 	return result
 }
+
+// evalArguments evaluates call arguments, expanding ...spread arguments
+// into the individual elements of the spread array and collecting name:
+// value arguments separately.
+func (e *Evaluator) evalArguments(
+	exps []ast.Expression,
+	env *object.Environment,
+) ([]object.Object, []namedArgument, object.Object) {
+	var result []object.Object
+	var named []namedArgument
+	for _, exp := range exps {
+		switch exp := exp.(type) {
+		case *ast.NamedArgument:
+			evaluated := e.Eval(exp.Value, env)
+			if isError(evaluated) {
+				return nil, nil, evaluated
+			}
+			named = append(named, namedArgument{name: exp.Name.Value, value: evaluated})
+		case *ast.SpreadExpression:
+			evaluated := e.Eval(exp.Value, env)
+			if isError(evaluated) {
+				return nil, nil, evaluated
+			}
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				return nil, nil, newError(object.TypeError, "cannot spread %s as arguments", evaluated.Type())
+			}
+			result = append(result, array.Elements...)
+		default:
+			evaluated := e.Eval(exp, env)
+			if isError(evaluated) {
+				return nil, nil, evaluated
+			}
+			result = append(result, evaluated)
+		}
+	}
+	return result, named, nil
+}
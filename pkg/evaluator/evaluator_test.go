@@ -1,11 +1,18 @@
 package evaluator_test
 
 import (
+	"context"
+	"fmt"
+	"github.com/muter3000/monkeparser/pkg/ast"
 	"github.com/muter3000/monkeparser/pkg/evaluator"
 	"github.com/muter3000/monkeparser/pkg/lexer"
+	"github.com/muter3000/monkeparser/pkg/module"
 	"github.com/muter3000/monkeparser/pkg/object"
 	"github.com/muter3000/monkeparser/pkg/parser"
+	"github.com/muter3000/monkeparser/pkg/token"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestEvalIntegerExpression(t *testing.T) {
@@ -34,6 +41,45 @@ func TestEvalIntegerExpression(t *testing.T) {
 	}
 }
 
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"2.5", "2.5"},
+		{"-2.5", "-2.5"},
+		{"1.5 + 1.5", "3.0"},
+		{"1 + 0.5", "1.5"},
+		{"0.5 * 4", "2.0"},
+		{"7 / 2.0", "3.5"},
+		{"1e21", "1e+21"},
+		{"1e308 * 10.0", "+Inf"},
+		{"1.5 < 2", "true"},
+		{"2 >= 2.0", "true"},
+		{"0.1 + 0.2 == 0.3", "false"},
+		{"1.0 == 1", "true"},
+		{"!0.0", "true"},
+		{"if (0.5) { 1 } else { 2 }", "1"},
+		{"match (2.5) { 2.5 => \"yes\", _ => \"no\" }", "yes"},
+		{"{1.5: \"a\", -0.0: \"b\"}[0.0]", "b"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	// No operator of the language reaches the error, so build the expression.
+	program := &ast.Program{Statements: []ast.Statement{&ast.ExpressionStatement{
+		Expression: &ast.InfixExpression{Operator: "%", Left: &ast.IntegerLiteral{Value: 7}, Right: &ast.FloatLiteral{Value: 2}},
+	}}}
+	evaluated := evaluator.Eval(program, object.NewEnvironment())
+	if evaluated.Inspect() != "ERROR: unknown operator: INTEGER % FLOAT" {
+		t.Errorf("wrong result for 7 %% 2.0. got=%q", evaluated.Inspect())
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -103,6 +149,39 @@ func TestIfElseExpressions(t *testing.T) {
 	}
 }
 
+func TestNullExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"null", nil},
+		{"null == null", true},
+		{"null != null", false},
+		{"1 == null", false},
+		{"1 != null", true},
+		{"null == false", false},
+		{"fn(x) { x } == null", false},
+		{"if (false) { 1 } == null", true},
+		{"null ?? 5", 5},
+		{"3 ?? 5", 3},
+		{"false ?? 5", false},
+		{"null ?? null ?? 7", 7},
+		{"let f = fn() { null }; f() ?? 2 * 3", 6},
+		{"1 ?? foobar", 1},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
 func testNullObject(t *testing.T, obj object.Object) bool {
 	if obj != evaluator.NULL {
 		t.Errorf("object is not NULL. got=%T (%+v)", obj, obj)
@@ -196,10 +275,50 @@ func TestErrorHandling(t *testing.T) {
 			"-true",
 			"unknown operator: -BOOLEAN",
 		},
+		{
+			"1 / 0",
+			"division by zero",
+		},
+		{
+			"1.5 / 0",
+			"division by zero",
+		},
+		{
+			"1.5 + true",
+			"type mismatch: FLOAT + BOOLEAN",
+		},
 		{
 			"true + false;",
 			"unknown operator: BOOLEAN + BOOLEAN",
 		},
+		{
+			"null + 1",
+			"unknown operator: NULL + INTEGER",
+		},
+		{
+			"null ?? foobar",
+			"identifier not found: foobar",
+		},
+		{
+			`"Hello" - "World"`,
+			"unknown operator: STRING - STRING",
+		},
+		{
+			`{"name": "Monke"}[fn(x) { x }];`,
+			"unusable as hash key: FUNCTION",
+		},
+		{
+			"1[0]",
+			"index operator not supported: INTEGER",
+		},
+		{
+			"null.name",
+			"member access not supported: NULL.name",
+		},
+		{
+			"match (3) { 1 => 1, 2 => 2 }",
+			"no match arm for value: 3",
+		},
 		{
 			"5; true + false; 5",
 			"unknown operator: BOOLEAN + BOOLEAN",
@@ -297,3 +416,908 @@ let addTwo = newAdder(2);
 addTwo(2);`
 	testIntegerObject(t, testEval(input), 4)
 }
+
+func TestStringLiteral(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"Hello World!"`, "Hello World!"},
+		{`"Hello" + " " + "World!"`, "Hello World!"},
+		{`"a" == "a"`, true},
+		{`"a" != "a"`, false},
+		{`"a" == "b"`, false},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case string:
+			testStringObject(t, evaluated, expected)
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		}
+	}
+}
+
+func testStringObject(t *testing.T, obj object.Object, expected string) bool {
+	result, ok := obj.(*object.String)
+	if !ok {
+		t.Errorf("object is not String. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%q, want=%q", result.Value, expected)
+		return false
+	}
+	return true
+}
+
+func TestArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+	if len(result.Elements) != 3 {
+		t.Fatalf("array has wrong num of elements. got=%d", len(result.Elements))
+	}
+	testIntegerObject(t, result.Elements[0], 1)
+	testIntegerObject(t, result.Elements[1], 4)
+	testIntegerObject(t, result.Elements[2], 6)
+}
+
+func TestHashLiterals(t *testing.T) {
+	input := `let two = "two";
+	{
+		"one": 10 - 9,
+		two: 1 + 1,
+		"thr" + "ee": 6 / 2,
+		4: 4,
+		true: 5,
+		false: 6
+	}`
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Hash)
+	if !ok {
+		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
+	}
+	expected := map[object.HashKey]int64{
+		(&object.String{Value: "one"}).HashKey():   1,
+		(&object.String{Value: "two"}).HashKey():   2,
+		(&object.String{Value: "three"}).HashKey(): 3,
+		(&object.Integer{Value: 4}).HashKey():      4,
+		evaluator.TRUE.HashKey():                   5,
+		evaluator.FALSE.HashKey():                  6,
+	}
+	if len(result.Pairs) != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	}
+	for expectedKey, expectedValue := range expected {
+		pair, ok := result.Pairs[expectedKey]
+		if !ok {
+			t.Errorf("no pair for given key in Pairs")
+		}
+		testIntegerObject(t, pair.Value, expectedValue)
+	}
+	if result.Inspect() != `{4: 4, false: 6, one: 1, three: 3, true: 5, two: 2}` {
+		t.Errorf("hash has wrong Inspect. got=%q", result.Inspect())
+	}
+}
+
+func TestIndexAndMemberExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3][0]", 1},
+		{"[1, 2, 3][1 + 1]", 3},
+		{"let myArray = [1, 2, 3]; myArray[2];", 3},
+		{"[1, 2, 3][3]", nil},
+		{"[1, 2, 3][-1]", nil},
+		{`{"foo": 5}["foo"]`, 5},
+		{`{"foo": 5}["bar"]`, nil},
+		{`{5: 5}[5]`, 5},
+		{`{true: 5}[true]`, 5},
+		{`{"foo": 5}.foo`, 5},
+		{`{"foo": 5}.bar`, nil},
+		{`let p = {"pos": {"x": 1, "y": 2}}; p.pos.y`, 2},
+		{`let p = {"f": fn(x) { x * 2 }}; p.f(4)`, 8},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestOptionalChaining(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"null?.a", nil},
+		{"null?[0]", nil},
+		{"null?.a.b.c", nil},
+		{"null?.a[0].b", nil},
+		{"null?.f(1, 2)", nil},
+		{"null?.a.b(undefinedArg)", nil},
+		{`{"a": null}.a?.b`, nil},
+		{`{"a": {"b": 1}}?.a?.b`, 1},
+		{`[[1, 2]]?[0]?[1]`, 2},
+		{`{"a": null}.a?.b ?? 3`, 3},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestConditionalExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"true ? 1 : 2", 1},
+		{"false ? 1 : 2", 2},
+		{"null ? 1 : 2", 2},
+		{"1 > 2 ? 1 : 2 < 3 ? 3 : 4", 3},
+		{"let abs = fn(x) { x < 0 ? -x : x }; abs(-5) + abs(5)", 10},
+	}
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`match (1) { 1 => "one", _ => "other" }`, "one"},
+		{`match (2) { 1 => "one", _ => "other" }`, "other"},
+		{`match (-1) { -1 => "minus one", _ => "other" }`, "minus one"},
+		{`match (1.0) { 1 => "int", _ => "other" }`, "int"},
+		{`match (2) { 1.5 => "x", 2.0 => "float", _ => "other" }`, "float"},
+		{`match (1.5) { 1 => "int", _ => "other" }`, "other"},
+		{`match ("a") { "a" => "A", "b" => "B" }`, "A"},
+		{`match (null) { null => "null", _ => "other" }`, "null"},
+		{`match (true) { false => "no", true => "yes" }`, "yes"},
+		{`match (5) { n if n > 10 => "big", n => "small" }`, "small"},
+		{`match (50) { n if n > 10 => "big", n => "small" }`, "big"},
+		{`match ([]) { [] => "empty", _ => "other" }`, "empty"},
+		{`match ([1]) { [x] => x, _ => 0 }`, 1},
+		{`match ([1, 2]) { [x] => x, [x, y] => x + y }`, 3},
+		{`match ([1, 2, 3]) { [h, ..t] => t[1] }`, 3},
+		{`match ([1]) { [h, ..t] => t[0] ?? "empty tail" }`, "empty tail"},
+		{`match ([1, 2]) { [1, x] => x, _ => 0 }`, 2},
+		{`match ([3, 2]) { [1, x] => x, _ => 0 }`, 0},
+		{`match ({"name": "Ann", "age": 30}) { {name, age} => age }`, 30},
+		{`match ({"name": "Ann"}) { {name, age} => "both", {name} => name }`, "Ann"},
+		{`match ({"tags": ["a", "b"]}) { {"tags": [first, .._]} => first }`, "a"},
+		{`match (5) { [x] => x, {a} => a, x => x }`, 5},
+		{`let x = 1; match (2) { x => x }; x`, 1},
+		{`let f = fn(list) { match (list) { [] => 0, [h, ..t] => h + f(t) } }; f([1, 2, 3, 4])`, 10},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testStringObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestDestructuring(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let [a, b] = [1, 2]; a + b", 3},
+		{"let [a, b, ..rest] = [1, 2, 3, 4]; rest[0] + rest[1]", 7},
+		{"let [a, ..rest] = [1]; rest[0] ?? a", 1},
+		{"let [_, second] = [1, 2]; second", 2},
+		{`let {name, age} = {"name": "Ann", "age": 30}; age`, 30},
+		{`let {pos: {x, y}} = {"pos": {"x": 3, "y": 4}}; x * y`, 12},
+		{`let [{"v": v}, [w]] = [{"v": 5}, [6]]; v + w`, 11},
+		{"let add = fn([a, b]) { a + b }; add([2, 3])", 5},
+		{`let area = fn({w, h}) { w * h }; area({"w": 2, "h": 5})`, 10},
+		{"let f = fn(x, [y, ..ys]) { x + y + ys[0] }; f(1, [2, 3])", 6},
+	}
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestDestructuringErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{
+			"let [a, b] = 5;",
+			"cannot match INTEGER against array pattern [a, b]",
+		},
+		{
+			"let [a, b] = [1];",
+			"array pattern [a, b] expects 2 elements, got 1",
+		},
+		{
+			"let [a, b, ..c] = [1];",
+			"array pattern [a, b, ..c] expects at least 2 elements, got 1",
+		},
+		{
+			`let {name, age} = {"name": "Ann"};`,
+			`key "age" not found for hash pattern {name, age}`,
+		},
+		{
+			"let {name} = [1];",
+			"cannot match ARRAY against hash pattern {name}",
+		},
+		{
+			"let f = fn([a]) { a }; f(1)",
+			"cannot match INTEGER against array pattern [a]",
+		},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for '%s'. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message for '%s'. expected=%q, got=%q", tt.input,
+				tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestFunctionArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let f = fn(x, y = 10) { x + y }; f(1)", 11},
+		{"let f = fn(x, y = 10) { x + y }; f(1, 2)", 3},
+		{"let f = fn(x, y = x * 2) { x + y }; f(3)", 9},
+		{"let f = fn(x = 1, y = 2) { x * 10 + y }; f()", 12},
+		{"let f = fn([a, b] = [1, 2]) { a + b }; f()", 3},
+		{"let f = fn(first, ...rest) { rest[1] }; f(1, 2, 3)", 3},
+		{"let f = fn(first, ...rest) { rest[0] ?? first }; f(1)", 1},
+		{"let f = fn(x, y = 5, ...rest) { y }; f(1)", 5},
+		{"let add = fn(a, b, c) { a + b + c }; add(...[1, 2, 3])", 6},
+		{"let add = fn(a, b, c) { a + b + c }; add(1, ...[2], ...[], 3)", 6},
+		{"let count = fn(...xs) { match (xs) { [] => 0, [_, ..t] => 1 + count(...t) } }; count(5, 6, 7)", 3},
+	}
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestFunctionArgumentErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"fn(a, b) { a }(1)", "wrong number of arguments. got=1, want=2"},
+		{"fn(a) { a }(1, 2)", "wrong number of arguments. got=2, want=1"},
+		{"fn(a, b = 1) { a }()", "wrong number of arguments. got=0, want=1..2"},
+		{"fn(a, b = 1) { a }(1, 2, 3)", "wrong number of arguments. got=3, want=1..2"},
+		{"fn(a, ...b) { a }()", "wrong number of arguments. got=0, want=1+"},
+		{"fn(a, b = c) { a }(1)", "identifier not found: c"},
+		{"fn(a) { a }(...1)", "cannot spread INTEGER as arguments"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for '%s'. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message for '%s'. expected=%q, got=%q", tt.input,
+				tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestBuiltinFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`len("")`, 0},
+		{`len("four")`, 4},
+		{`len([1, 2, 3])`, 3},
+		{`len({"a": 1})`, 1},
+		{`len(1)`, "argument to `len` not supported, got INTEGER"},
+		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
+		{`first([1, 2, 3])`, 1},
+		{`first([])`, nil},
+		{`last([1, 2, 3])`, 3},
+		{`rest([1, 2, 3])[1]`, 3},
+		{`push([1], 2)[1]`, 2},
+		{`push(value: 2, array: [1])[1]`, 2},
+		{`len(value: [1, 2])`, 2},
+		{`len(array: [1, 2])`, "unknown parameter name in call to len: array"},
+		{`push(value: 2)`, "missing argument for parameter array"},
+		{`push([1], array: [2])`, "duplicate argument for parameter array"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestNamedArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let f = fn(a, b) { a * 10 + b }; f(b: 2, a: 1)", 12},
+		{"let f = fn(a, b) { a * 10 + b }; f(1, b: 2)", 12},
+		{"let f = fn(a, b = 5, c = 7) { a * 100 + b * 10 + c }; f(1, c: 9)", 159},
+		{"let f = fn(a, [b, c]) { a + b + c }; f(1, [2, 3])", 6},
+		{"let f = fn(a, ...rest) { a + len(rest) }; f(a: 1)", 1},
+		{"let f = fn(a, b) { a }; f(1, c: 2)", "unknown parameter name: c"},
+		{"let f = fn(a, b) { a }; f(1, a: 2)", "duplicate argument for parameter a"},
+		{"let f = fn(a, b) { a }; f(a: 1, a: 2)", "duplicate argument for parameter a"},
+		{"let f = fn(a, b) { a }; f(b: 1)", "missing argument for parameter a"},
+		{"let f = fn(a) { a }; f(1, 2, a: 3)", "wrong number of arguments. got=3, want=1"},
+		{"let f = fn([a]) { a }; f(a: 1)", "unknown parameter name: a"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestHostBuiltinNamedArguments(t *testing.T) {
+	env := object.NewEnvironment()
+	env.Set("deploy", &object.Builtin{
+		Name:       "deploy",
+		Parameters: []string{"replicas", "canary"},
+		Fn: func(args ...object.Object) object.Object {
+			canary := len(args) > 1 && args[1] == evaluator.TRUE
+			return &object.String{Value: fmt.Sprintf("%s replicas, canary=%t", args[0].Inspect(), canary)}
+		},
+	})
+	env.Set("positional", &object.Builtin{
+		Name: "positional",
+		Fn: func(args ...object.Object) object.Object {
+			return args[0]
+		},
+	})
+
+	l := lexer.New(`deploy(replicas: 3, canary: true) + "; " + deploy(canary: false, replicas: 2) + "; " + deploy(1)`)
+	p := parser.New(l)
+	evaluated := evaluator.Eval(p.ParseProgram(), env)
+	testStringObject(t, evaluated, "3 replicas, canary=true; 2 replicas, canary=false; 1 replicas, canary=false")
+
+	l = lexer.New(`positional(x: 1)`)
+	p = parser.New(l)
+	evaluated = evaluator.Eval(p.ParseProgram(), env)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "builtin positional does not accept named arguments" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestErrorPositionAndStack(t *testing.T) {
+	input := `let inner = fn(x) {
+  x + missing
+};
+let outer = fn(x) {
+  inner(x) * 2
+};
+let run = fn() { outer(1) };
+run()`
+	evaluated := testEval(input)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Pos != (token.Position{Line: 2, Column: 7}) {
+		t.Errorf("wrong error position. got=%s", errObj.Pos)
+	}
+	expected := []object.Frame{
+		{Function: "inner", CallSite: token.Position{Line: 5, Column: 3}},
+		{Function: "outer", CallSite: token.Position{Line: 7, Column: 18}},
+		{Function: "run", CallSite: token.Position{Line: 8, Column: 1}},
+	}
+	if len(errObj.Stack) != len(expected) {
+		t.Fatalf("wrong stack length. got=%+v", errObj.Stack)
+	}
+	for i, frame := range expected {
+		if errObj.Stack[i] != frame {
+			t.Errorf("wrong frame %d. want=%+v, got=%+v", i, frame, errObj.Stack[i])
+		}
+	}
+
+	traceback := `ERROR: identifier not found: missing
+    at inner (2:7)
+    at outer (5:3)
+    at run (7:18)
+    at <main> (8:1)`
+	if errObj.Traceback() != traceback {
+		t.Errorf("wrong traceback.\nwant=%s\ngot=%s", traceback, errObj.Traceback())
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"5 + true", "ERROR: type mismatch: INTEGER + BOOLEAN\n    at <main> (1:3)"},
+		{"let x = 1;\n  -true", "ERROR: unknown operator: -BOOLEAN\n    at <main> (2:3)"},
+		{"let [a] = 1", "ERROR: cannot match INTEGER against array pattern [a]\n    at <main> (1:1)"},
+		{"fn(a) { a }()", "ERROR: wrong number of arguments. got=0, want=1\n    at <main> (1:1)"},
+		{"fn() { len(1) }()", "ERROR: argument to `len` not supported, got INTEGER\n    at <anonymous> (1:8)\n    at <main> (1:1)"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Traceback() != tt.expected {
+			t.Errorf("wrong traceback for %q.\nwant=%s\ngot=%s", tt.input, tt.expected, errObj.Traceback())
+		}
+	}
+}
+
+func TestTryCatchFinally(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"try { 1 } catch (e) { 2 }", 1},
+		{"try { missing } catch (e) { 2 }", 2},
+		{"try { missing } catch { 2 }", 2},
+		{"try { missing } catch (e) { e.message }", "identifier not found: missing"},
+		{"try { missing } catch (e) { e.kind }", "NameError"},
+		{"try { 1 + true } catch (e) { e.kind }", "TypeError"},
+		{"try { fn(a) { a }() } catch ({kind}) { kind }", "ArgumentError"},
+		{"try { match (1) { 2 => 2 } } catch ({kind}) { kind }", "MatchError"},
+		{"try { len(1) } catch ({kind}) { kind }", "TypeError"},
+		{"try { throw 42 } catch (e) { e }", 42},
+		{`try { throw "boom" } catch (e) { e }`, "boom"},
+		{`try { throw {"code": 7} } catch ({code}) { code }`, 7},
+		{"let f = fn() { throw 1; 2 }; try { f() } catch (e) { e + 10 }", 11},
+		{"let log = fn(x) { x }; let x = try { 1 } finally { log(2) }; x", 1},
+		{"let f = fn() { try { return 1 } finally { 2 } }; f()", 1},
+		{"let f = fn() { try { return 1 } finally { return 2 } }; f()", 2},
+		{"let f = fn() { try { missing } catch (e) { return 3 } finally { 4 }; 5 }; f()", 3},
+		{"let f = fn() { try { missing } finally { return 6 } }; f()", 6},
+		{"let f = fn() { try { 1 } catch (e) { 2 }; 7 }; f()", 7},
+		{`try { try { missing } catch (e) { throw e } } catch (e) { e.kind + ": " + e.message }`, "NameError: identifier not found: missing"},
+		{`try { try { throw 1 } finally { throw 2 } } catch (e) { e }`, 2},
+		{`let inner = fn() { missing }; let outer = fn() { inner() };
+		  try { outer() } catch (e) { e.stack[1].function }`, "outer"},
+		{"let inner = fn() {\n    missing\n};\ntry { inner() } catch (e) { e.stack[0].line * 100 + e.stack[0].column }", 205},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testStringObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestUncaughtThrow(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedKind string
+		expectedMsg  string
+	}{
+		{`throw "boom"`, object.ThrownError, "boom"},
+		{`throw 42`, object.ThrownError, "42"},
+		{`throw {"kind": "IOError", "message": "disk full"}`, object.IOError, "disk full"},
+		{`try { missing } catch (e) { 1 } finally { throw "late" }`, object.ThrownError, "late"},
+		{`try { 1 } catch ([a]) { a }; try { missing } catch ([a]) { a }`, object.MatchError, "cannot match HASH against array pattern [a]"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Kind != tt.expectedKind || errObj.Message != tt.expectedMsg {
+			t.Errorf("wrong error for %q. want=%s %q, got=%s %q", tt.input,
+				tt.expectedKind, tt.expectedMsg, errObj.Kind, errObj.Message)
+		}
+	}
+}
+
+func TestCatchBuiltinErrorKind(t *testing.T) {
+	env := object.NewEnvironment()
+	env.Set("read", &object.Builtin{
+		Name: "read",
+		Fn: func(args ...object.Object) object.Object {
+			return object.NewError(object.IOError, "cannot read %s", args[0].Inspect())
+		},
+	})
+	l := lexer.New(`try { read("config") } catch (e) { e.kind == "IOError" ? e.message : "other" }`)
+	p := parser.New(l)
+	evaluated := evaluator.Eval(p.ParseProgram(), env)
+	testStringObject(t, evaluated, "cannot read config")
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let count = fn(n, acc) { if (n == 0) { acc } else { count(n - 1, acc + 1) } }; count(1000000, 0)", 1000000},
+		{"let count = fn(n) { if (n == 0) { return 0 }; count(n - 1) }; count(100000)", 0},
+		{"let count = fn(n) { if (n > 0) { return count(n - 1) }; 7 }; count(100000)", 7},
+		{"let count = fn(n) { n == 0 ? 1 : count(n - 1) }; count(100000)", 1},
+		{"let count = fn(n) { match (n) { 0 => 2, _ => count(n - 1) } }; count(100000)", 2},
+		{"let count = fn(n, acc = 0) { if (n == 0) { acc } else { count(n - 1, acc: acc + n) } }; count(100000)", 5000050000},
+		{`let isEven = fn(n) { if (n == 0) { true } else { isOdd(n - 1) } };
+		  let isOdd = fn(n) { if (n == 0) { false } else { isEven(n - 1) } };
+		  if (isEven(1000000)) { 1 } else { 0 }`, 1},
+		{"let sum = fn(n) { if (n == 0) { 0 } else { n + sum(n - 1) } }; sum(100)", 5050},
+		{"let f = fn(n) { if (n == 0) { len([1, 2]) } else { f(n - 1) } }; f(10)", 2},
+	}
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestTailCallErrorStack(t *testing.T) {
+	input := `let fail = fn() { missing };
+let count = fn(n) { if (n == 0) { fail() } else { count(n - 1) } };
+let run = fn() { count(3) };
+run()`
+	evaluated := testEval(input)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	traceback := `ERROR: identifier not found: missing
+    at fail (1:19)
+    at count (2:35)
+    at count (2:51)
+    at run (3:18)
+    at <main> (4:1)`
+	if errObj.Traceback() != traceback {
+		t.Errorf("wrong traceback.\nwant=%s\ngot=%s", traceback, errObj.Traceback())
+	}
+}
+
+func TestRecursionDepthLimit(t *testing.T) {
+	evaluated := testEval("let f = fn(n) { f(n + 1) + 1 }; f(0)")
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Kind != object.RecursionError || errObj.Message != "maximum recursion depth exceeded" {
+		t.Errorf("wrong error. got=%s: %s", errObj.Kind, errObj.Message)
+	}
+	if len(errObj.Stack) != object.MaxStackFrames {
+		t.Errorf("wrong stack length. got=%d", len(errObj.Stack))
+	}
+	if errObj.Omitted != evaluator.DefaultMaxDepth-object.MaxStackFrames {
+		t.Errorf("wrong number of omitted frames. got=%d", errObj.Omitted)
+	}
+	if errObj.Stack[0] != (object.Frame{Function: "f", CallSite: token.Position{Line: 1, Column: 17}}) {
+		t.Errorf("wrong innermost frame. got=%+v", errObj.Stack[0])
+	}
+
+	traceback := errObj.Traceback()
+	tail := fmt.Sprintf("\n    at f (1:17)\n    ... %d more frames\n    at <main>", errObj.Omitted)
+	if len(traceback) < len(tail) || traceback[len(traceback)-len(tail):] != tail {
+		t.Errorf("wrong end of traceback. got=%s", traceback)
+	}
+
+	caught := testEval("let f = fn(n) { f(n + 1) + 1 }; try { f(0) } catch (e) { e.kind }")
+	testStringObject(t, caught, object.RecursionError)
+}
+
+func TestMaxDepth(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(49)", 49},
+		{"let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(50)", "maximum recursion depth exceeded"},
+		{"let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(1000)", 0},
+	}
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		e := evaluator.New()
+		e.MaxDepth = 50
+		evaluated := e.Eval(program, object.NewEnvironment())
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. want=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestEvalContextCancellation(t *testing.T) {
+	tests := []struct {
+		input   string
+		timeout time.Duration
+	}{
+		{"let loop = fn() { loop() }; loop()", 20 * time.Millisecond},
+		{"let loop = fn(n) { loop(n + 1) }; try { loop(0) } catch (e) { 1 }", 20 * time.Millisecond},
+		{"let loop = fn() { loop() }; try { loop() } finally { 1 }", 20 * time.Millisecond},
+		{"let f = fn() { 1 }; f()", 0},
+	}
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+		evaluated := evaluator.EvalContext(ctx, program, object.NewEnvironment())
+		cancel()
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("object is not Error for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Kind != object.CancelledError || !errObj.Fatal {
+			t.Errorf("wrong error for %q. got=%s (fatal=%t)", tt.input, errObj.Kind, errObj.Fatal)
+		}
+		if errObj.Message != "evaluation cancelled: context deadline exceeded" {
+			t.Errorf("wrong error message for %q. got=%q", tt.input, errObj.Message)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	program := parser.New(lexer.New("1 + 2")).ParseProgram()
+	testIntegerObject(t, evaluator.EvalContext(ctx, program, object.NewEnvironment()), 3)
+}
+
+func TestUsage(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected evaluator.Usage
+	}{
+		{"1 + 2", evaluator.Usage{Steps: 3, Memory: 48}},
+		{"true == false", evaluator.Usage{Steps: 3, Memory: 0}},
+		{"let x = [1, 2]", evaluator.Usage{Steps: 4, Memory: 16 + 16 + 64 + 48}},
+		{`"ab"`, evaluator.Usage{Steps: 1, Memory: 26}},
+		{"let f = fn(a) { a }; f(1)", evaluator.Usage{Steps: 6, Memory: 80 + 48 + 16 + 64 + 48}},
+	}
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		e := evaluator.New()
+		e.Eval(program, object.NewEnvironment())
+		if e.Usage() != tt.expected {
+			t.Errorf("wrong usage for %q. want=%+v, got=%+v", tt.input, tt.expected, e.Usage())
+		}
+	}
+}
+
+func TestExecutionLimits(t *testing.T) {
+	tests := []struct {
+		input     string
+		maxSteps  int64
+		maxMemory int64
+		expected  string
+	}{
+		{"let loop = fn() { loop() }; loop()", 1000, 0, "step limit of 1000 exceeded"},
+		{"let loop = fn() { loop() }; try { loop() } catch (e) { 1 }", 1000, 0, "step limit of 1000 exceeded"},
+		{"let grow = fn(a) { grow(push(a, a)) }; grow([])", 0, 1 << 20, "memory limit of 1048576 bytes exceeded"},
+		{`let grow = fn(s) { grow(s + s) }; grow("x")`, 0, 1 << 20, "memory limit of 1048576 bytes exceeded"},
+		{"let a = [1, 2, 3, 4, 5, 6, 7, 8]; match (a) { [h, ..t] => t }", 0, 450, "memory limit of 450 bytes exceeded"},
+	}
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		e := evaluator.New()
+		e.MaxSteps = tt.maxSteps
+		e.MaxMemory = tt.maxMemory
+		evaluated := e.Eval(program, object.NewEnvironment())
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("object is not Error for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Kind != object.LimitError || !errObj.Fatal || errObj.Message != tt.expected {
+			t.Errorf("wrong error for %q. got=%s: %s (fatal=%t)", tt.input, errObj.Kind, errObj.Message, errObj.Fatal)
+		}
+		usage := e.Usage()
+		if tt.maxSteps > 0 && usage.Steps != tt.maxSteps+1 {
+			t.Errorf("wrong steps for %q. got=%d", tt.input, usage.Steps)
+		}
+		if tt.maxMemory > 0 && usage.Memory <= tt.maxMemory {
+			t.Errorf("wrong memory for %q. got=%d", tt.input, usage.Memory)
+		}
+	}
+}
+
+func TestFrozenEnvironment(t *testing.T) {
+	env := object.NewEnvironment()
+	env.Freeze()
+	program := parser.New(lexer.New("let x = 1")).ParseProgram()
+	evaluated := evaluator.Eval(program, env)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "cannot bind x in a frozen environment" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestSharedPreludeConcurrently(t *testing.T) {
+	prelude := object.NewEnvironment()
+	library := `
+let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } };
+let config = {"base": 100, "names": ["a", "b"]};
+let make_adder = fn(k) { fn(x) { x + k } };
+`
+	evaluator.Eval(parser.New(lexer.New(library)).ParseProgram(), prelude)
+	prelude.Freeze()
+
+	var wg sync.WaitGroup
+	for n := 0; n < 32; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			env := object.NewEnclosedEnvironment(prelude)
+			env.Set("n", &object.Integer{Value: int64(n)})
+			script := "let add = make_adder(n); let fib = fn(x) { 0 }; add(config.base) + fib(15) + len(config.names)"
+			evaluated := evaluator.Eval(parser.New(lexer.New(script)).ParseProgram(), env)
+			testIntegerObject(t, evaluated, int64(100+n+2))
+
+			evaluated = evaluator.Eval(parser.New(lexer.New("fib(15)")).ParseProgram(), object.NewEnclosedEnvironment(prelude))
+			testIntegerObject(t, evaluated, 610)
+		}(n)
+	}
+	wg.Wait()
+}
+
+func evalModules(e *evaluator.Evaluator, input string) object.Object {
+	program := parser.New(lexer.New(input)).ParseProgram()
+	return e.Eval(program, object.NewEnvironment())
+}
+
+func TestModules(t *testing.T) {
+	loader := module.Map{
+		"lib/math.mk": `
+			import "util.mk" as util;
+			export let square = fn(x) { util.mul(x, x) };
+			export let [one, two] = [1, 2];
+			let hidden = 3;`,
+		"lib/util.mk": `export let mul = fn(a, b) { a * b };`,
+		"counter.mk":  `let loads = 1; export let loads = loads;`,
+		"a.mk":        `import "b.mk" as b; export let a = 1;`,
+		"b.mk":        `import "c.mk" as c; export let b = 2;`,
+		"c.mk":        `import "a.mk" as a; export let c = 3;`,
+		"broken.mk":   `let x = ;`,
+		"failing.mk":  `export let x = 1; missing`,
+	}
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`import "lib/math.mk" as m; m.square(4)`, 16},
+		{`import "lib/math.mk" as m; m.one + m.two`, 3},
+		{`import "/lib/util.mk" as u; u.mul(2, 3)`, 6},
+		{`import "lib/math.mk" as m; m.hidden`, "module lib/math.mk has no export hidden"},
+		{`import "lib/math.mk" as m; m?.nothing`, "module lib/math.mk has no export nothing"},
+		{`import "a.mk" as a; a.a`, "import cycle: a.mk -> b.mk -> c.mk -> a.mk"},
+		{`import "missing.mk" as m;`, "cannot import missing.mk: open missing.mk: file does not exist"},
+		{`import "../up.mk" as m;`, `cannot import ../up.mk: import path "../up.mk" escapes the module root`},
+		{`import "broken.mk" as m;`, "cannot import broken.mk: no prefix parse function for ; found"},
+		{`import "failing.mk" as m;`, "identifier not found: missing"},
+		{`export let x = 1;`, "export is only allowed at the top level of a module"},
+		{`try { import "a.mk" as a; } catch (e) { e.kind }`, "ImportError"},
+	}
+	for _, tt := range tests {
+		e := evaluator.New()
+		e.Loader = loader
+		evaluated := evalModules(e, tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			if str, ok := evaluated.(*object.String); ok {
+				testStringObject(t, str, expected)
+				continue
+			}
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestModulesEvaluatedOnce(t *testing.T) {
+	loads := 0
+	e := evaluator.New()
+	e.Loader = module.Map{"log.mk": `loaded(); export let x = 1;`}
+	e.Builtins = evaluator.DefaultBuiltins()
+	e.Builtins["loaded"] = &object.Builtin{Name: "loaded", Fn: func(args ...object.Object) object.Object {
+		loads++
+		return evaluator.NULL
+	}}
+	evaluated := evalModules(e, `import "log.mk" as a; import "log.mk" as b; a.x + b.x`)
+	testIntegerObject(t, evaluated, 2)
+	evaluated = evalModules(e, `import "./log.mk" as c; c.x`)
+	testIntegerObject(t, evaluated, 1)
+	if loads != 1 {
+		t.Errorf("module evaluated %d times, expected once", loads)
+	}
+}
+
+func TestModuleErrorTraceback(t *testing.T) {
+	e := evaluator.New()
+	e.Loader = module.Map{"lib.mk": "let f = fn() { missing };\nf();"}
+	evaluated := evalModules(e, `import "lib.mk" as lib;`)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	expected := "ERROR: identifier not found: missing\n" +
+		"    at f (1:16)\n" +
+		"    at <module lib.mk> (2:1)\n" +
+		"    at <main> (1:1)"
+	if errObj.Traceback() != expected {
+		t.Errorf("wrong traceback.\nexpected=%q\ngot=%q", expected, errObj.Traceback())
+	}
+}
@@ -0,0 +1,109 @@
+package evaluator
+
+import (
+	"bufio"
+	"errors"
+	"github.com/muter3000/monkeparser/pkg/object"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+func builtinPrint(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+	return e.write(self, args, "")
+}
+
+func builtinPrintln(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+	return e.write(self, args, "\n")
+}
+
+// write writes args to Stdout separated by spaces and followed by end.
+// Strings are written without quotes.
+func (e *Evaluator) write(self *object.Builtin, args []object.Object, end string) object.Object {
+	if e.Stdout == nil {
+		return NULL
+	}
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = arg.Inspect()
+	}
+	if _, err := io.WriteString(e.Stdout, strings.Join(parts, " ")+end); err != nil {
+		return newError(object.IOError, "%s: %s", self.Name, err)
+	}
+	return NULL
+}
+
+// builtinReadLine reads a line from Stdin without its line ending. At the
+// end of the input it returns null.
+func builtinReadLine(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+	if err := checkArgCount(args, 0, 0); err != nil {
+		return err
+	}
+	if e.Stdin == nil {
+		return newError(object.PermissionError, "%s is not permitted: no input", self.Name)
+	}
+	if e.lines == nil {
+		e.lines = bufio.NewReader(e.Stdin)
+	}
+	line, err := e.lines.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return NULL
+	}
+	if err != nil && err != io.EOF {
+		return newError(object.IOError, "%s: %s", self.Name, err)
+	}
+	if err := e.checkAllocation(int64(len(line))); err != nil {
+		return err
+	}
+	line = strings.TrimSuffix(line, "\n")
+	return &object.String{Value: strings.TrimSuffix(line, "\r")}
+}
+
+func builtinReadFile(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+	if err := checkArgCount(args, 1, 1); err != nil {
+		return err
+	}
+	path, err := stringArg(self, args, 0)
+	if err != nil {
+		return err
+	}
+	if e.FS == nil {
+		return newError(object.PermissionError, "%s is not permitted: no filesystem", self.Name)
+	}
+	if info, statErr := fs.Stat(e.FS, path); statErr == nil {
+		if err := e.checkAllocation(info.Size()); err != nil {
+			return err
+		}
+	}
+	data, readErr := fs.ReadFile(e.FS, path)
+	if readErr != nil {
+		return fileError(readErr)
+	}
+	return &object.String{Value: string(data)}
+}
+
+func builtinWriteFile(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+	if err := checkArgCount(args, 2, 2); err != nil {
+		return err
+	}
+	path, content, err := twoStringArgs(self, args)
+	if err != nil {
+		return err
+	}
+	if e.WriteFS == nil {
+		return newError(object.PermissionError, "%s is not permitted: no writable filesystem", self.Name)
+	}
+	if writeErr := e.WriteFS.WriteFile(path, []byte(content)); writeErr != nil {
+		return fileError(writeErr)
+	}
+	return NULL
+}
+
+// fileError converts an error from a filesystem. Permissions the host's
+// filesystem denies are PermissionErrors like missing capabilities.
+func fileError(err error) *object.Error {
+	if errors.Is(err, fs.ErrPermission) {
+		return newError(object.PermissionError, "%s", err)
+	}
+	return newError(object.IOError, "%s", err)
+}
@@ -0,0 +1,157 @@
+package evaluator_test
+
+import (
+	"context"
+	"github.com/muter3000/monkeparser/pkg/evaluator"
+	"github.com/muter3000/monkeparser/pkg/lexer"
+	"github.com/muter3000/monkeparser/pkg/object"
+	"github.com/muter3000/monkeparser/pkg/parser"
+	"github.com/muter3000/monkeparser/pkg/vfs"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func evalWith(e *evaluator.Evaluator, input string) object.Object {
+	program := parser.New(lexer.New(input)).ParseProgram()
+	return e.Eval(program, object.NewEnvironment())
+}
+
+func TestPrint(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`print("a", 1, [true, "b"], {"k": null})`, "a 1 [true, b] {k: null}"},
+		{`println("a"); println(); print("b")`, "a\n\nb"},
+		{`let say = fn(p) { p("hi") }; say(println)`, "hi\n"},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		e := evaluator.New()
+		e.Stdout = &out
+		result := evalWith(e, tt.input)
+		testNullObject(t, result)
+		if out.String() != tt.expected {
+			t.Errorf("wrong output for %q. want=%q, got=%q", tt.input, tt.expected, out.String())
+		}
+	}
+
+	e := evaluator.New()
+	e.Stdout = nil
+	testNullObject(t, evalWith(e, `print("discarded")`))
+}
+
+func TestReadLine(t *testing.T) {
+	e := evaluator.New()
+	e.Stdin = strings.NewReader("one\r\ntwo\n\nlast")
+	result := evalWith(e, `[read_line(), read_line(), read_line(), read_line(), read_line()]`)
+	if result.Inspect() != "[one, two, , last, null]" {
+		t.Errorf("wrong lines. got=%s", result.Inspect())
+	}
+
+	e = evaluator.New()
+	e.Stdin = strings.NewReader("a very long line\n")
+	e.MaxMemory = 8
+	testErrorKind(t, evalWith(e, `read_line()`), object.LimitError)
+}
+
+func TestFileBuiltins(t *testing.T) {
+	fsys := vfs.NewMemory(map[string]string{"notes.txt": "hello", "dir/a.txt": "a"})
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`read_file("notes.txt")`, "hello"},
+		{`write_file("notes.txt", "bye"); read_file("notes.txt")`, "bye"},
+		{`write_file(path: "new.txt", content: "new"); read_file("new.txt")`, "new"},
+		{`read_file("missing.txt")`, errorMessage("open missing.txt: file does not exist")},
+		{`read_file("../etc/passwd")`, errorMessage("open ../etc/passwd: invalid argument")},
+		{`write_file("dir", "x")`, errorMessage("write dir: is a directory")},
+		{`try { read_file("missing.txt") } catch (e) { e.kind }`, "IOError"},
+		{`read_file(1)`, errorMessage("argument `path` to `read_file` must be STRING, got INTEGER")},
+		{`write_file("a")`, errorMessage("wrong number of arguments. got=1, want=2")},
+	}
+	for _, tt := range tests {
+		e := evaluator.New()
+		e.FS = fsys
+		e.WriteFS = fsys
+		evaluated := evalWith(e, tt.input)
+		switch expected := tt.expected.(type) {
+		case string:
+			testStringObject(t, evaluated, expected)
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			}
+		}
+	}
+
+	e := evaluator.New()
+	e.FS = fsys
+	e.MaxMemory = 4
+	testErrorKind(t, evalWith(e, `read_file("notes.txt")`), object.LimitError)
+}
+
+func TestIOPermissions(t *testing.T) {
+	tests := []struct {
+		setup    func(e *evaluator.Evaluator)
+		input    string
+		expected string
+	}{
+		{func(e *evaluator.Evaluator) {}, `read_file("a")`, "read_file is not permitted: no filesystem"},
+		{func(e *evaluator.Evaluator) { e.FS = vfs.NewMemory(map[string]string{"a": "a"}) }, `write_file("a", "b")`, "write_file is not permitted: no writable filesystem"},
+		{func(e *evaluator.Evaluator) {}, `read_line()`, "read_line is not permitted: no input"},
+		{
+			func(e *evaluator.Evaluator) { e.FS = deniedFS{} },
+			`read_file("a")`, "open a: permission denied",
+		},
+	}
+	for _, tt := range tests {
+		e := evaluator.New()
+		tt.setup(e)
+		evaluated := evalWith(e, `try { `+tt.input+` } catch (e) { [e.kind, e.message] }`)
+		expected := "[PermissionError, " + tt.expected + "]"
+		if evaluated.Inspect() != expected {
+			t.Errorf("wrong error for %q. want=%s, got=%s", tt.input, expected, evaluated.Inspect())
+		}
+	}
+}
+
+func testErrorKind(t *testing.T, obj object.Object, kind string) {
+	t.Helper()
+	errObj, ok := obj.(*object.Error)
+	if !ok || errObj.Kind != kind {
+		t.Errorf("expected %s. got=%T (%+v)", kind, obj, obj)
+	}
+}
+
+// deniedFS is a filesystem whose host denies every access.
+type deniedFS struct{}
+
+func (deniedFS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+}
+
+func TestEvaluatorBuiltinsFromGo(t *testing.T) {
+	var out strings.Builder
+	e := evaluator.New()
+	e.Stdout = &out
+	println := evaluator.DefaultBuiltins()["println"]
+
+	result := println.Fn(&object.String{Value: "x"})
+	errObj, ok := result.(*object.Error)
+	if !ok || errObj.Message != "builtin println must be called by an evaluator" {
+		t.Errorf("wrong result calling Fn directly. got=%s", result.Inspect())
+	}
+
+	testNullObject(t, e.ApplyContext(context.Background(), println, &object.String{Value: "x"}))
+	if out.String() != "x\n" {
+		t.Errorf("wrong output. got=%q", out.String())
+	}
+}
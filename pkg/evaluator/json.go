@@ -0,0 +1,217 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/muter3000/monkeparser/pkg/object"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// newJSONModule creates the json module. Hash keys are written in sorted
+// order so that the same value always stringifies the same way.
+func newJSONModule() *object.Module {
+	return newNativeModule("json",
+		nativeFunction("json.parse", []string{"text"}, 1, func(self *object.Builtin, args []object.Object) object.Object {
+			text, err := stringArg(self, args, 0)
+			if err != nil {
+				return err
+			}
+			return parseJSON(text)
+		}),
+		nativeEvaluatorFunction("json.stringify", []string{"value", "indent"}, 1, func(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+			var out bytes.Buffer
+			if err := e.writeJSON(&out, args[0], 0); err != nil {
+				return err
+			}
+			if len(args) == 1 {
+				return &object.String{Value: out.String()}
+			}
+			var indent string
+			switch arg := args[1].(type) {
+			case *object.Integer:
+				if arg.Value < 0 || arg.Value > 10 {
+					return newError(object.ArgumentError, "indent %d out of range 0..10", arg.Value)
+				}
+				indent = strings.Repeat(" ", int(arg.Value))
+			case *object.String:
+				indent = arg.Value
+			default:
+				return argTypeError(self, 1, "INTEGER or STRING", args[1])
+			}
+			var indented bytes.Buffer
+			if err := json.Indent(&indented, out.Bytes(), "", indent); err != nil {
+				return newError(object.RuntimeError, "cannot indent JSON: %s", err)
+			}
+			if err := e.checkAllocation(int64(indented.Len())); err != nil {
+				return err
+			}
+			return &object.String{Value: indented.String()}
+		}),
+	)
+}
+
+// parseJSON converts a JSON document to objects. Integers must fit in an
+// INTEGER; numbers with a fraction or exponent become floats.
+func parseJSON(text string) object.Object {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var value interface{}
+	err := dec.Decode(&value)
+	if err == nil {
+		rest := strings.TrimLeft(text[dec.InputOffset():], " \t\r\n")
+		if rest != "" {
+			return jsonSyntaxError(text, int64(len(text)-len(rest)), "unexpected data after top-level value")
+		}
+	}
+	if err != nil {
+		var syntaxErr *json.SyntaxError
+		switch {
+		case errors.As(err, &syntaxErr):
+			// The offset is just past the offending byte
+			return jsonSyntaxError(text, syntaxErr.Offset-1, strings.TrimPrefix(syntaxErr.Error(), "json: "))
+		case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+			return jsonSyntaxError(text, int64(len(text)), "unexpected end of JSON input")
+		default:
+			return newError(object.ArgumentError, "invalid JSON: %s", err)
+		}
+	}
+	return jsonToObject(value)
+}
+
+// jsonSyntaxError reports an error at a byte offset of text as a line and
+// column, both counted from one like source positions.
+func jsonSyntaxError(text string, offset int64, message string) *object.Error {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > int64(len(text)) {
+		offset = int64(len(text))
+	}
+	before := text[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := len(before) - strings.LastIndex(before, "\n")
+	return newError(object.ArgumentError, "invalid JSON at %d:%d: %s", line, column, message)
+}
+
+func jsonToObject(value interface{}) object.Object {
+	switch value := value.(type) {
+	case nil:
+		return NULL
+	case bool:
+		return nativeBoolToBooleanObject(value)
+	case string:
+		return &object.String{Value: value}
+	case json.Number:
+		literal := value.String()
+		if !strings.ContainsAny(literal, ".eE") {
+			i, err := strconv.ParseInt(literal, 10, 64)
+			if err != nil {
+				return newError(object.ArgumentError, "JSON integer %s out of range for INTEGER", literal)
+			}
+			return &object.Integer{Value: i}
+		}
+		f, err := strconv.ParseFloat(literal, 64)
+		if err != nil {
+			return newError(object.ArgumentError, "JSON number %s out of range for FLOAT", literal)
+		}
+		return &object.Float{Value: f}
+	case []interface{}:
+		elements := make([]object.Object, len(value))
+		for i, el := range value {
+			obj := jsonToObject(el)
+			if isError(obj) {
+				return obj
+			}
+			elements[i] = obj
+		}
+		return &object.Array{Elements: elements}
+	case map[string]interface{}:
+		pairs := make(map[object.HashKey]object.HashPair, len(value))
+		for k, v := range value {
+			obj := jsonToObject(v)
+			if isError(obj) {
+				return obj
+			}
+			key := &object.String{Value: k}
+			pairs[key.HashKey()] = object.HashPair{Key: key, Value: obj}
+		}
+		return &object.Hash{Pairs: pairs}
+	default:
+		return newError(object.RuntimeError, "unexpected JSON value %v", value)
+	}
+}
+
+// maxJSONDepth bounds the nesting that stringify follows.
+const maxJSONDepth = 1000
+
+// writeJSON appends obj to out, checking the memory limit after every value
+// so that shared values nested many times over fail before the output grows
+// without bound.
+func (e *Evaluator) writeJSON(out *bytes.Buffer, obj object.Object, depth int) *object.Error {
+	if depth > maxJSONDepth {
+		return newError(object.ArgumentError, "cannot stringify value nested deeper than %d", maxJSONDepth)
+	}
+	switch obj := obj.(type) {
+	case *object.Null:
+		out.WriteString("null")
+	case *object.Boolean, *object.Integer:
+		out.WriteString(obj.Inspect())
+	case *object.Float:
+		if math.IsNaN(obj.Value) || math.IsInf(obj.Value, 0) {
+			return newError(object.TypeError, "cannot stringify %s", obj.Inspect())
+		}
+		out.WriteString(obj.Inspect())
+	case *object.String:
+		writeJSONString(out, obj.Value)
+	case *object.Array:
+		out.WriteByte('[')
+		for i, el := range obj.Elements {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if err := e.writeJSON(out, el, depth+1); err != nil {
+				return err
+			}
+		}
+		out.WriteByte(']')
+	case *object.Hash:
+		keys := make([]string, 0, len(obj.Pairs))
+		values := make(map[string]object.Object, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			key, ok := pair.Key.(*object.String)
+			if !ok {
+				return newError(object.TypeError, "cannot stringify hash key of type %s", pair.Key.Type())
+			}
+			keys = append(keys, key.Value)
+			values[key.Value] = pair.Value
+		}
+		sort.Strings(keys)
+		out.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			writeJSONString(out, key)
+			out.WriteByte(':')
+			if err := e.writeJSON(out, values[key], depth+1); err != nil {
+				return err
+			}
+		}
+		out.WriteByte('}')
+	default:
+		return newError(object.TypeError, "cannot stringify %s", obj.Type())
+	}
+	return e.checkAllocation(int64(out.Len()))
+}
+
+func writeJSONString(out *bytes.Buffer, s string) {
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	out.Truncate(out.Len() - 1) // Encode adds a newline
+}
@@ -0,0 +1,67 @@
+package evaluator_test
+
+import (
+	"github.com/muter3000/monkeparser/pkg/evaluator"
+	"testing"
+)
+
+func TestJSONParse(t *testing.T) {
+	testModule(t, "json", []moduleTest{
+		{`json.parse("42")`, 42},
+		{`json.parse("-2.5")`, -2.5},
+		{`json.parse("1e3")`, 1000.0},
+		{`json.parse("\"h\\u00e9\"")`, "hé"},
+		{`json.parse("true")`, true},
+		{`json.parse("null") == null`, true},
+		{`json.parse("[1, [2, 3]]")[1][0]`, 2},
+		{`json.parse("{\"a\": {\"b\": [null, \"x\"]}}").a.b[1]`, "x"},
+		{`len(json.parse("{\"a\": 1, \"a\": 2, \"b\": 3}"))`, 2},
+		{`json.parse("9223372036854775807")`, 9223372036854775807},
+		{`json.parse("9223372036854775808")`, errorMessage("JSON integer 9223372036854775808 out of range for INTEGER")},
+		{`json.parse("[1, 123456789012345678901234567890]")`, errorMessage("JSON integer 123456789012345678901234567890 out of range for INTEGER")},
+		{`json.parse("1e400")`, errorMessage("JSON number 1e400 out of range for FLOAT")},
+		{`json.parse("{\"a\": x}")`, errorMessage("invalid JSON at 1:7: invalid character 'x' looking for beginning of value")},
+		{`json.parse("[1,\n 2,\n ]")`, errorMessage("invalid JSON at 3:2: invalid character ']' looking for beginning of value")},
+		{`json.parse("[1, 2")`, errorMessage("invalid JSON at 1:6: unexpected end of JSON input")},
+		{`json.parse("")`, errorMessage("invalid JSON at 1:1: unexpected end of JSON input")},
+		{`json.parse("1 2")`, errorMessage("invalid JSON at 1:3: unexpected data after top-level value")},
+		{`json.parse(1)`, errorMessage("argument `text` to `json.parse` must be STRING, got INTEGER")},
+		{`try { json.parse("{") } catch (e) { e.kind }`, "ArgumentError"},
+	})
+}
+
+func TestJSONStringify(t *testing.T) {
+	testModule(t, "json", []moduleTest{
+		{`json.stringify(null)`, "null"},
+		{`json.stringify(true)`, "true"},
+		{`json.stringify(-7)`, "-7"},
+		{`json.stringify(2.0)`, "2.0"},
+		{`json.stringify(1e21)`, "1e+21"},
+		{`json.stringify("a \"quoted\" <tag>\n")`, `"a \"quoted\" <tag>\n"`},
+		{`json.stringify([1, "two", [null]])`, `[1,"two",[null]]`},
+		{`json.stringify({"b": 1, "a": [true], "c": {"z": 1, "y": 2}})`, `{"a":[true],"b":1,"c":{"y":2,"z":1}}`},
+		{`json.stringify({"b": [1, 2], "a": {}}, 2)`, "{\n  \"a\": {},\n  \"b\": [\n    1,\n    2\n  ]\n}"},
+		{`json.stringify([1], "\t")`, "[\n\t1\n]"},
+		{`json.stringify(json.parse("{\"big\": 9007199254740993}"))`, `{"big":9007199254740993}`},
+		{`json.stringify(fn(x) { x })`, errorMessage("cannot stringify FUNCTION")},
+		{`json.stringify([len])`, errorMessage("cannot stringify BUILTIN")},
+		{`json.stringify({1: "one"})`, errorMessage("cannot stringify hash key of type INTEGER")},
+		{`json.stringify(1e308 * 10.0)`, errorMessage("cannot stringify +Inf")},
+		{`json.stringify(1, -1)`, errorMessage("indent -1 out of range 0..10")},
+		{`json.stringify(1, true)`, errorMessage("argument `indent` to `json.stringify` must be INTEGER or STRING, got BOOLEAN")},
+	})
+}
+
+func TestJSONStringifyMemoryLimit(t *testing.T) {
+	limited := func() *evaluator.Evaluator {
+		e := evaluator.New()
+		e.MaxMemory = 10000
+		return e
+	}
+	nest := `let nest = fn(a, n) { if (n == 0) { a } else { nest([a, a], n - 1) } }; `
+	testModuleWith(t, limited, "json", []moduleTest{
+		{nest + `len(json.stringify(nest([1], 4)))`, 93},
+		{nest + `json.stringify(nest([1], 30))`, errorMessage("memory limit of 10000 bytes exceeded")},
+		{nest + `json.stringify(nest([1], 8), 10)`, errorMessage("memory limit of 10000 bytes exceeded")},
+	})
+}
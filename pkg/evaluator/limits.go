@@ -0,0 +1,97 @@
+package evaluator
+
+import (
+	"github.com/muter3000/monkeparser/pkg/ast"
+	"github.com/muter3000/monkeparser/pkg/object"
+)
+
+// Usage reports the resources an Evaluator has used since it was created.
+type Usage struct {
+	Steps  int64 // Nodes evaluated
+	Memory int64 // Approximate bytes allocated for objects and environments
+}
+
+// Approximate sizes in bytes charged against the memory budget. They follow
+// the Go representation of the objects but are not exact.
+const (
+	integerSize     = 16
+	floatSize       = 16
+	stringSize      = 24 // Plus one byte per byte of the string
+	arraySize       = 32 // Plus elementSize per element
+	elementSize     = 16
+	hashSize        = 48 // Plus pairSize per pair
+	pairSize        = 64
+	functionSize    = 80
+	environmentSize = 64
+	bindingSize     = 48 // Per name set in an environment
+)
+
+func (e *Evaluator) Usage() Usage {
+	return Usage{Steps: e.steps, Memory: e.memory}
+}
+
+// step counts the evaluation of node against MaxSteps. Programs, blocks and
+// expression statements only group other nodes and are free.
+func (e *Evaluator) step(node ast.Node) *object.Error {
+	switch node.(type) {
+	case *ast.Program, *ast.BlockStatement, *ast.ExpressionStatement:
+		return nil
+	}
+	e.steps++
+	if e.MaxSteps > 0 && e.steps > e.MaxSteps {
+		return limitError("step limit of %d exceeded", e.MaxSteps)
+	}
+	return nil
+}
+
+// charge counts size bytes of allocation against MaxMemory.
+func (e *Evaluator) charge(size int64) *object.Error {
+	e.memory += size
+	if e.MaxMemory > 0 && e.memory > e.MaxMemory {
+		return limitError("memory limit of %d bytes exceeded", e.MaxMemory)
+	}
+	return nil
+}
+
+// track charges for obj, a newly created object, and returns it, or the
+// error if that goes over the memory limit.
+func (e *Evaluator) track(obj object.Object) object.Object {
+	if err := e.charge(objectSize(obj)); err != nil {
+		return err
+	}
+	return obj
+}
+
+// newEnclosedEnvironment creates and charges for a new environment.
+func (e *Evaluator) newEnclosedEnvironment(outer *object.Environment) (*object.Environment, *object.Error) {
+	if err := e.charge(environmentSize); err != nil {
+		return nil, err
+	}
+	return object.NewEnclosedEnvironment(outer), nil
+}
+
+func objectSize(obj object.Object) int64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return integerSize
+	case *object.Float:
+		return floatSize
+	case *object.String:
+		return stringSize + int64(len(obj.Value))
+	case *object.Array:
+		return arraySize + elementSize*int64(len(obj.Elements))
+	case *object.Hash:
+		return hashSize + pairSize*int64(len(obj.Pairs))
+	case *object.Function:
+		return functionSize
+	default:
+		// Booleans and null are shared, errors end the evaluation
+		return 0
+	}
+}
+
+func limitError(format string, a ...interface{}) *object.Error {
+	err := newError(object.LimitError, format, a...)
+	err.Fatal = true
+	return err
+}
@@ -0,0 +1,324 @@
+package evaluator
+
+import (
+	"github.com/muter3000/monkeparser/pkg/object"
+	"math"
+)
+
+// newMathModule creates the math module. Functions that take numbers accept
+// integers and floats alike. Results that are not real numbers, such as the
+// square root of a negative number, raise errors instead of returning NaN.
+func newMathModule() *object.Module {
+	mod := newNativeModule("math",
+		nativeFunction("math.abs", []string{"x"}, 1, func(self *object.Builtin, args []object.Object) object.Object {
+			switch x := args[0].(type) {
+			case *object.Integer:
+				if x.Value == math.MinInt64 {
+					return integerOverflow(self)
+				}
+				if x.Value < 0 {
+					return &object.Integer{Value: -x.Value}
+				}
+				return x
+			case *object.Float:
+				return &object.Float{Value: math.Abs(x.Value)}
+			default:
+				return argTypeError(self, 0, numberType, args[0])
+			}
+		}),
+		extremumFunction("math.min", func(a, b float64) bool { return a < b }),
+		extremumFunction("math.max", func(a, b float64) bool { return a > b }),
+		nativeFunction("math.pow", []string{"base", "exp"}, 2, func(self *object.Builtin, args []object.Object) object.Object {
+			base, baseOk := args[0].(*object.Integer)
+			exp, expOk := args[1].(*object.Integer)
+			if baseOk && expOk && exp.Value >= 0 {
+				result, ok := powInt64(base.Value, exp.Value)
+				if !ok {
+					return integerOverflow(self)
+				}
+				return &object.Integer{Value: result}
+			}
+			x, y, err := twoFloatArgs(self, args)
+			if err != nil {
+				return err
+			}
+			return floatResult(self, math.Pow(x, y))
+		}),
+		floatFunction("math.sqrt", math.Sqrt),
+		floatFunction("math.exp", math.Exp),
+		nativeFunction("math.log", []string{"x", "base"}, 1, func(self *object.Builtin, args []object.Object) object.Object {
+			x, err := floatArg(self, args, 0)
+			if err != nil {
+				return err
+			}
+			if x <= 0 {
+				return domainError(self)
+			}
+			if len(args) == 1 {
+				return floatResult(self, math.Log(x))
+			}
+			base, err := floatArg(self, args, 1)
+			if err != nil {
+				return err
+			}
+			if base <= 0 || base == 1 {
+				return domainError(self)
+			}
+			return floatResult(self, math.Log(x)/math.Log(base))
+		}),
+		floatFunction("math.sin", math.Sin),
+		floatFunction("math.cos", math.Cos),
+		floatFunction("math.tan", math.Tan),
+		floatFunction("math.asin", math.Asin),
+		floatFunction("math.acos", math.Acos),
+		floatFunction("math.atan", math.Atan),
+		nativeFunction("math.atan2", []string{"y", "x"}, 2, func(self *object.Builtin, args []object.Object) object.Object {
+			y, x, err := twoFloatArgs(self, args)
+			if err != nil {
+				return err
+			}
+			return floatResult(self, math.Atan2(y, x))
+		}),
+		roundingFunction("math.floor", math.Floor),
+		roundingFunction("math.ceil", math.Ceil),
+		roundingFunction("math.round", math.Round),
+		nativeFunction("math.gcd", []string{"a", "b"}, 2, func(self *object.Builtin, args []object.Object) object.Object {
+			a, b, err := twoIntegerArgs(self, args)
+			if err != nil {
+				return err
+			}
+			gcd := gcdUint64(absUint64(a), absUint64(b))
+			if gcd > math.MaxInt64 {
+				return integerOverflow(self)
+			}
+			return &object.Integer{Value: int64(gcd)}
+		}),
+		nativeFunction("math.lcm", []string{"a", "b"}, 2, func(self *object.Builtin, args []object.Object) object.Object {
+			a, b, err := twoIntegerArgs(self, args)
+			if err != nil {
+				return err
+			}
+			if a == 0 || b == 0 {
+				return &object.Integer{Value: 0}
+			}
+			ua, ub := absUint64(a), absUint64(b)
+			quotient := ua / gcdUint64(ua, ub)
+			if quotient > math.MaxInt64/ub {
+				return integerOverflow(self)
+			}
+			return &object.Integer{Value: int64(quotient * ub)}
+		}),
+		nativeFunction("math.clamp", []string{"x", "min", "max"}, 3, func(self *object.Builtin, args []object.Object) object.Object {
+			for i, arg := range args {
+				if !isNumber(arg) {
+					return argTypeError(self, i, numberType, arg)
+				}
+			}
+			x, low, high := args[0], args[1], args[2]
+			if toFloat(low) > toFloat(high) {
+				return newError(object.ArgumentError, "min %s greater than max %s in call to %s", low.Inspect(), high.Inspect(), self.Name)
+			}
+			switch {
+			case toFloat(x) < toFloat(low):
+				return low
+			case toFloat(x) > toFloat(high):
+				return high
+			default:
+				return x
+			}
+		}),
+		nativeFunction("math.div_floor", []string{"a", "b"}, 2, func(self *object.Builtin, args []object.Object) object.Object {
+			a, b, err := twoIntegerArgs(self, args)
+			if err != nil {
+				return err
+			}
+			if b == 0 {
+				return newError(object.RuntimeError, "division by zero")
+			}
+			if a == math.MinInt64 && b == -1 {
+				return integerOverflow(self)
+			}
+			q := a / b
+			if a%b != 0 && (a < 0) != (b < 0) {
+				q--
+			}
+			return &object.Integer{Value: q}
+		}),
+		nativeFunction("math.mod_floor", []string{"a", "b"}, 2, func(self *object.Builtin, args []object.Object) object.Object {
+			a, b, err := twoIntegerArgs(self, args)
+			if err != nil {
+				return err
+			}
+			if b == 0 {
+				return newError(object.RuntimeError, "division by zero")
+			}
+			m := a % b
+			if m != 0 && (m < 0) != (b < 0) {
+				m += b
+			}
+			return &object.Integer{Value: m}
+		}),
+	)
+	mod.Exports["PI"] = &object.Float{Value: math.Pi}
+	mod.Exports["E"] = &object.Float{Value: math.E}
+	return mod
+}
+
+// numberType names the accepted types in errors for number arguments.
+const numberType object.ObjectType = "INTEGER or FLOAT"
+
+// floatArg returns argument i of a call to fn, an integer or a float, as a
+// float.
+func floatArg(fn *object.Builtin, args []object.Object, i int) (float64, *object.Error) {
+	if !isNumber(args[i]) {
+		return 0, argTypeError(fn, i, numberType, args[i])
+	}
+	return toFloat(args[i]), nil
+}
+
+func twoFloatArgs(fn *object.Builtin, args []object.Object) (float64, float64, *object.Error) {
+	a, err := floatArg(fn, args, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	b, err := floatArg(fn, args, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	return a, b, nil
+}
+
+func twoIntegerArgs(fn *object.Builtin, args []object.Object) (int64, int64, *object.Error) {
+	a, err := integerArg(fn, args, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	b, err := integerArg(fn, args, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	return a, b, nil
+}
+
+// floatResult returns v, or an error if fn produced NaN or an infinity
+// from finite arguments.
+func floatResult(fn *object.Builtin, v float64) object.Object {
+	if math.IsNaN(v) {
+		return domainError(fn)
+	}
+	if math.IsInf(v, 0) {
+		return newError(object.ArgumentError, "result of %s out of range", fn.Name)
+	}
+	return &object.Float{Value: v}
+}
+
+func domainError(fn *object.Builtin) *object.Error {
+	return newError(object.ArgumentError, "argument to %s out of domain", fn.Name)
+}
+
+func integerOverflow(fn *object.Builtin) *object.Error {
+	return newError(object.ArgumentError, "integer overflow in call to %s", fn.Name)
+}
+
+func floatFunction(name string, fn func(float64) float64) *object.Builtin {
+	return nativeFunction(name, []string{"x"}, 1, func(self *object.Builtin, args []object.Object) object.Object {
+		x, err := floatArg(self, args, 0)
+		if err != nil {
+			return err
+		}
+		return floatResult(self, fn(x))
+	})
+}
+
+// roundingFunction rounds floats to integers with fn. Integers are returned
+// unchanged.
+func roundingFunction(name string, fn func(float64) float64) *object.Builtin {
+	return nativeFunction(name, []string{"x"}, 1, func(self *object.Builtin, args []object.Object) object.Object {
+		switch x := args[0].(type) {
+		case *object.Integer:
+			return x
+		case *object.Float:
+			rounded := fn(x.Value)
+			if math.IsNaN(rounded) || rounded < math.MinInt64 || rounded >= math.MaxInt64 {
+				return newError(object.ArgumentError, "result of %s out of range", self.Name)
+			}
+			return &object.Integer{Value: int64(rounded)}
+		default:
+			return argTypeError(self, 0, numberType, args[0])
+		}
+	})
+}
+
+// extremumFunction returns the argument that wins over all others by
+// better, keeping its type. It takes any number of arguments or a single
+// array.
+func extremumFunction(name string, better func(a, b float64) bool) *object.Builtin {
+	builtin := &object.Builtin{Name: name}
+	builtin.Fn = func(args ...object.Object) object.Object {
+		values := args
+		if len(args) == 1 {
+			if array, ok := args[0].(*object.Array); ok {
+				values = array.Elements
+			}
+		}
+		if len(values) == 0 {
+			return newError(object.ArgumentError, "%s of no values", name)
+		}
+		var best object.Object
+		for _, value := range values {
+			if !isNumber(value) {
+				return newError(object.TypeError, "arguments to `%s` must be %s, got %s", name, numberType, value.Type())
+			}
+			if best == nil || better(toFloat(value), toFloat(best)) {
+				best = value
+			}
+		}
+		return best
+	}
+	return builtin
+}
+
+// powInt64 raises base to a non-negative exponent, reporting overflow.
+func powInt64(base, exp int64) (int64, bool) {
+	result := int64(1)
+	for exp > 0 {
+		var ok bool
+		if exp&1 == 1 {
+			if result, ok = mulInt64(result, base); !ok {
+				return 0, false
+			}
+		}
+		exp >>= 1
+		if exp > 0 {
+			if base, ok = mulInt64(base, base); !ok {
+				return 0, false
+			}
+		}
+	}
+	return result, true
+}
+
+func mulInt64(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	c := a * b
+	if c/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+	return c, true
+}
+
+func absUint64(x int64) uint64 {
+	if x < 0 {
+		return uint64(-(x + 1)) + 1
+	}
+	return uint64(x)
+}
+
+func gcdUint64(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
@@ -0,0 +1,125 @@
+package evaluator_test
+
+import "testing"
+
+func TestMathAbs(t *testing.T) {
+	testModule(t, "math", []moduleTest{
+		{`math.abs(-3)`, 3},
+		{`math.abs(3)`, 3},
+		{`math.abs(-2.5)`, 2.5},
+		{`math.abs(-9223372036854775807 - 1)`, errorMessage("integer overflow in call to math.abs")},
+		{`math.abs("3")`, errorMessage("argument `x` to `math.abs` must be INTEGER or FLOAT, got STRING")},
+	})
+}
+
+func TestMathMinMax(t *testing.T) {
+	testModule(t, "math", []moduleTest{
+		{`math.min(3, 1, 2)`, 1},
+		{`math.min([3, 1.5, 2])`, 1.5},
+		{`math.max(3, 1, 2)`, 3},
+		{`math.max(1, 2.5)`, 2.5},
+		{`math.max(7)`, 7},
+		{`math.min()`, errorMessage("math.min of no values")},
+		{`math.max([])`, errorMessage("math.max of no values")},
+		{`math.max(1, "2")`, errorMessage("arguments to `math.max` must be INTEGER or FLOAT, got STRING")},
+	})
+}
+
+func TestMathPow(t *testing.T) {
+	testModule(t, "math", []moduleTest{
+		{`math.pow(2, 10)`, 1024},
+		{`math.pow(-3, 3)`, -27},
+		{`math.pow(5, 0)`, 1},
+		{`math.pow(2, -1)`, 0.5},
+		{`math.pow(2.0, 0.5)`, 1.4142135623730951},
+		{`math.pow(2, 63)`, errorMessage("integer overflow in call to math.pow")},
+		{`math.pow(-8, 1.0 / 3)`, errorMessage("argument to math.pow out of domain")},
+		{`math.pow(0, -1)`, errorMessage("result of math.pow out of range")},
+	})
+}
+
+func TestMathSqrtExpLog(t *testing.T) {
+	testModule(t, "math", []moduleTest{
+		{`math.sqrt(16)`, 4.0},
+		{`math.sqrt(-1)`, errorMessage("argument to math.sqrt out of domain")},
+		{`math.exp(0)`, 1.0},
+		{`math.exp(1000)`, errorMessage("result of math.exp out of range")},
+		{`math.log(math.E)`, 1.0},
+		{`math.log(8, 2)`, 3.0},
+		{`math.log(0)`, errorMessage("argument to math.log out of domain")},
+		{`math.log(8, 1)`, errorMessage("argument to math.log out of domain")},
+	})
+}
+
+func TestMathTrigonometry(t *testing.T) {
+	testModule(t, "math", []moduleTest{
+		{`math.sin(math.PI / 2)`, 1.0},
+		{`math.cos(0)`, 1.0},
+		{`math.tan(0)`, 0.0},
+		{`math.asin(1)`, 1.5707963267948966},
+		{`math.acos(1)`, 0.0},
+		{`math.atan(1)`, 0.7853981633974483},
+		{`math.atan2(1, -1)`, 2.356194490192345},
+		{`math.asin(2)`, errorMessage("argument to math.asin out of domain")},
+	})
+}
+
+func TestMathRounding(t *testing.T) {
+	testModule(t, "math", []moduleTest{
+		{`math.floor(2.7)`, 2},
+		{`math.floor(-2.5)`, -3},
+		{`math.ceil(2.1)`, 3},
+		{`math.round(2.5)`, 3},
+		{`math.round(-2.5)`, -3},
+		{`math.round(4)`, 4},
+		{`math.floor(1e300)`, errorMessage("result of math.floor out of range")},
+	})
+}
+
+func TestMathGcdLcm(t *testing.T) {
+	testModule(t, "math", []moduleTest{
+		{`math.gcd(12, 18)`, 6},
+		{`math.gcd(-12, 18)`, 6},
+		{`math.gcd(0, 0)`, 0},
+		{`math.lcm(4, 6)`, 12},
+		{`math.lcm(-4, 6)`, 12},
+		{`math.lcm(0, 6)`, 0},
+		{`math.lcm(9223372036854775807, 2)`, errorMessage("integer overflow in call to math.lcm")},
+		{`math.gcd(1.5, 2)`, errorMessage("argument `a` to `math.gcd` must be INTEGER, got FLOAT")},
+	})
+}
+
+func TestMathClamp(t *testing.T) {
+	testModule(t, "math", []moduleTest{
+		{`math.clamp(5, 0, 10)`, 5},
+		{`math.clamp(-5, 0, 10)`, 0},
+		{`math.clamp(15, 0, 10)`, 10},
+		{`math.clamp(0.5, 1, 2)`, 1},
+		{`math.clamp(1, 2, 0)`, errorMessage("min 2 greater than max 0 in call to math.clamp")},
+	})
+}
+
+func TestMathFloorDivision(t *testing.T) {
+	testModule(t, "math", []moduleTest{
+		{`math.div_floor(7, 2)`, 3},
+		{`math.div_floor(-7, 2)`, -4},
+		{`-7 / 2`, -3},
+		{`math.div_floor(7, -2)`, -4},
+		{`math.div_floor(-7, -2)`, 3},
+		{`math.mod_floor(7, 3)`, 1},
+		{`math.mod_floor(-7, 3)`, 2},
+		{`math.mod_floor(7, -3)`, -2},
+		{`math.mod_floor(-7, -3)`, -1},
+		{`math.mod_floor(-9223372036854775807 - 1, -1)`, 0},
+		{`math.div_floor(-9223372036854775807 - 1, -1)`, errorMessage("integer overflow in call to math.div_floor")},
+		{`math.div_floor(1, 0)`, errorMessage("division by zero")},
+		{`math.mod_floor(1, 0)`, errorMessage("division by zero")},
+	})
+}
+
+func TestMathConstants(t *testing.T) {
+	testModule(t, "math", []moduleTest{
+		{`math.PI`, 3.141592653589793},
+		{`math.E`, 2.718281828459045},
+	})
+}
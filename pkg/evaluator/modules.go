@@ -0,0 +1,141 @@
+package evaluator
+
+import (
+	"github.com/muter3000/monkeparser/pkg/ast"
+	"github.com/muter3000/monkeparser/pkg/lexer"
+	"github.com/muter3000/monkeparser/pkg/module"
+	"github.com/muter3000/monkeparser/pkg/object"
+	"github.com/muter3000/monkeparser/pkg/parser"
+	"github.com/muter3000/monkeparser/pkg/token"
+	"strings"
+)
+
+// moduleState tracks the module whose top level is being evaluated.
+type moduleState struct {
+	name    string
+	env     *object.Environment
+	exports []string
+}
+
+func (e *Evaluator) evalImportStatement(node *ast.ImportStatement, environment *object.Environment) object.Object {
+	from := ""
+	if e.module != nil {
+		from = e.module.name
+	}
+	mod := e.importModule(from, node.Path.Value, node.Pos())
+	if isError(mod) {
+		return mod
+	}
+	if err := e.bindPattern(node.Alias, mod, environment); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Import returns the module with the given name, as stored in
+// object.Module.Name: a native module, or a module file named relative to
+// the root. It imports the module unless it is already in e.Modules, and
+// returns an error object if the import fails.
+func (e *Evaluator) Import(name string) object.Object {
+	return e.importModule("", name, token.Position{})
+}
+
+// importModule returns the module imported as importPath by the module
+// named from: a native module, or a module file evaluated unless it is
+// already in e.Modules. Modules that
+// fail are not cached, so importing them again retries.
+func (e *Evaluator) importModule(from, importPath string, pos token.Position) object.Object {
+	if mod, ok := e.nativeModule(importPath); ok {
+		return mod
+	}
+	name, err := module.Resolve(from, importPath)
+	if err != nil {
+		return newError(object.ImportError, "cannot import %s: %s", importPath, err)
+	}
+	if mod, ok := e.Modules[name]; ok {
+		return mod
+	}
+	for i, loading := range e.importing {
+		if loading == name {
+			cycle := append(append([]string{}, e.importing[i:]...), name)
+			return newError(object.ImportError, "import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	loader := e.Loader
+	if loader == nil && e.FS != nil {
+		loader = module.FS(e.FS)
+	}
+	if loader == nil {
+		return newError(object.ImportError, "cannot import %s: no module loader", name)
+	}
+	src, err := loader.Load(name)
+	if err != nil {
+		return newError(object.ImportError, "cannot import %s: %s", name, err)
+	}
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return newError(object.ImportError, "cannot import %s: %s", name, strings.Join(p.Errors(), "; "))
+	}
+	if err := e.charge(environmentSize); err != nil {
+		return err
+	}
+
+	state := &moduleState{name: name, env: object.NewEnvironment()}
+	prev := e.module
+	e.module = state
+	e.importing = append(e.importing, name)
+	result := e.Eval(program, state.env)
+	e.importing = e.importing[:len(e.importing)-1]
+	e.module = prev
+
+	if err, ok := result.(*object.Error); ok {
+		if !err.Fatal {
+			err.PushFrame(object.Frame{Function: "<module " + name + ">", CallSite: pos})
+		}
+		return err
+	}
+	mod := &object.Module{Name: name, Exports: make(map[string]object.Object, len(state.exports))}
+	for _, export := range state.exports {
+		mod.Exports[export], _ = state.env.Get(export)
+	}
+	if e.Modules == nil {
+		e.Modules = make(map[string]*object.Module)
+	}
+	e.Modules[name] = mod
+	return mod
+}
+
+func (e *Evaluator) evalExportStatement(node *ast.ExportStatement, environment *object.Environment) object.Object {
+	if e.module == nil || e.module.env != environment {
+		return newError(object.RuntimeError, "export is only allowed at the top level of a module")
+	}
+	if result := e.Eval(node.Let, environment); isError(result) {
+		return result
+	}
+	e.module.exports = append(e.module.exports, patternNames(node.Let.Name)...)
+	return nil
+}
+
+// patternNames lists the names a pattern binds.
+func patternNames(pattern ast.Pattern) []string {
+	var names []string
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if !pattern.IsWildcard() {
+			names = append(names, pattern.Value)
+		}
+	case *ast.ArrayPattern:
+		for _, el := range pattern.Elements {
+			names = append(names, patternNames(el)...)
+		}
+		if pattern.Rest != nil {
+			names = append(names, patternNames(pattern.Rest)...)
+		}
+	case *ast.HashPattern:
+		for _, pair := range pattern.Pairs {
+			names = append(names, patternNames(pair.Value)...)
+		}
+	}
+	return names
+}
@@ -0,0 +1,64 @@
+package evaluator
+
+import (
+	"github.com/muter3000/monkeparser/pkg/object"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// newRandomModule creates the random module, which draws from the Rand of
+// the calling evaluator.
+func newRandomModule() *object.Module {
+	return newNativeModule("random",
+		nativeEvaluatorFunction("random.int", []string{"min", "max"}, 2, func(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+			low, high, err := twoIntegerArgs(self, args)
+			if err != nil {
+				return err
+			}
+			if low > high {
+				return newError(object.ArgumentError, "min %d greater than max %d in call to %s", low, high, self.Name)
+			}
+			span := high - low
+			if span < 0 || span == math.MaxInt64 {
+				return newError(object.ArgumentError, "range %d..%d too large in call to %s", low, high, self.Name)
+			}
+			return &object.Integer{Value: low + e.random().Int63n(span+1)}
+		}),
+		nativeEvaluatorFunction("random.float", nil, 0, func(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+			return &object.Float{Value: e.random().Float64()}
+		}),
+		nativeEvaluatorFunction("random.choice", []string{"array"}, 1, func(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+			array, ok := args[0].(*object.Array)
+			if !ok {
+				return argTypeError(self, 0, object.ARRAY_OBJ, args[0])
+			}
+			if len(array.Elements) == 0 {
+				return newError(object.ArgumentError, "%s from an empty array", self.Name)
+			}
+			return array.Elements[e.random().Intn(len(array.Elements))]
+		}),
+		nativeEvaluatorFunction("random.shuffle", []string{"array"}, 1, func(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+			array, ok := args[0].(*object.Array)
+			if !ok {
+				return argTypeError(self, 0, object.ARRAY_OBJ, args[0])
+			}
+			elements := make([]object.Object, len(array.Elements))
+			copy(elements, array.Elements)
+			e.random().Shuffle(len(elements), func(i, j int) { elements[i], elements[j] = elements[j], elements[i] })
+			return &object.Array{Elements: elements}
+		}),
+	)
+}
+
+// random returns e.Rand, or if it is nil a source of the evaluator's own
+// seeded from the current time.
+func (e *Evaluator) random() *rand.Rand {
+	if e.Rand != nil {
+		return e.Rand
+	}
+	if e.ownRand == nil {
+		e.ownRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return e.ownRand
+}
@@ -0,0 +1,52 @@
+package evaluator_test
+
+import (
+	"github.com/muter3000/monkeparser/pkg/evaluator"
+	"github.com/muter3000/monkeparser/pkg/lexer"
+	"github.com/muter3000/monkeparser/pkg/object"
+	"github.com/muter3000/monkeparser/pkg/parser"
+	"math/rand"
+	"testing"
+)
+
+func seededEvaluator() *evaluator.Evaluator {
+	e := evaluator.New()
+	e.Rand = rand.New(rand.NewSource(1))
+	return e
+}
+
+func TestRandom(t *testing.T) {
+	testModuleWith(t, seededEvaluator, "random", []moduleTest{
+		{`let x = random.int(1, 6); if (x >= 1) { x <= 6 } else { false }`, true},
+		{`random.int(5, 5)`, 5},
+		{`let x = random.float(); if (x >= 0) { x < 1 } else { false }`, true},
+		{`random.choice(["only"])`, "only"},
+		{`len(random.shuffle([1, 2, 3]))`, 3},
+		{`let a = [1, 2, 3]; random.shuffle(a); a`, inspected("[1, 2, 3]")},
+		{`random.int(6, 1)`, errorMessage("min 6 greater than max 1 in call to random.int")},
+		{`random.int(-9223372036854775807 - 1, 9223372036854775807)`, errorMessage("range -9223372036854775808..9223372036854775807 too large in call to random.int")},
+		{`random.choice([])`, errorMessage("random.choice from an empty array")},
+		{`random.shuffle("abc")`, errorMessage("argument `array` to `random.shuffle` must be ARRAY, got STRING")},
+	})
+}
+
+func TestRandomSeeded(t *testing.T) {
+	p := parser.New(lexer.New(`
+		import "random" as random;
+		[random.int(0, 1000000), random.float(), random.choice([1, 2, 3, 4, 5, 6, 7, 8]), random.shuffle([1, 2, 3, 4, 5, 6, 7, 8])]
+	`))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	first := seededEvaluator().Eval(program, object.NewEnvironment()).Inspect()
+	second := seededEvaluator().Eval(program, object.NewEnvironment()).Inspect()
+	if first != second {
+		t.Errorf("same seed gave different values: %s and %s", first, second)
+	}
+	other := evaluator.New()
+	other.Rand = rand.New(rand.NewSource(2))
+	if third := other.Eval(program, object.NewEnvironment()).Inspect(); third == first {
+		t.Errorf("different seeds gave the same values: %s", third)
+	}
+}
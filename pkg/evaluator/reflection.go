@@ -0,0 +1,116 @@
+package evaluator
+
+import (
+	"github.com/muter3000/monkeparser/pkg/object"
+)
+
+func builtinType(self *object.Builtin, args []object.Object) object.Object {
+	return &object.String{Value: string(args[0].Type())}
+}
+
+func builtinInspect(self *object.Builtin, args []object.Object) object.Object {
+	return &object.String{Value: args[0].Inspect()}
+}
+
+func builtinIsCallable(self *object.Builtin, args []object.Object) object.Object {
+	_, err := functionArg(self, args, 0)
+	return nativeBoolToBooleanObject(err == nil)
+}
+
+// builtinArity returns how many parameters a function declares, counting
+// optional ones but not a rest parameter. Builtins that declare no
+// parameters take any number of arguments, so their arity is null.
+func builtinArity(self *object.Builtin, args []object.Object) object.Object {
+	params, err := parameterList(self, args)
+	if err != nil {
+		return err
+	}
+	if builtin, ok := args[0].(*object.Builtin); ok && builtin.Parameters == nil {
+		return NULL
+	}
+	if fn, ok := args[0].(*object.Function); ok && fn.Rest != nil {
+		return &object.Integer{Value: int64(len(params) - 1)}
+	}
+	return &object.Integer{Value: int64(len(params))}
+}
+
+// builtinParams returns the parameters of a function as they are written,
+// with their defaults and a leading ... for a rest parameter.
+func builtinParams(self *object.Builtin, args []object.Object) object.Object {
+	params, err := parameterList(self, args)
+	if err != nil {
+		return err
+	}
+	return stringArray(params)
+}
+
+func parameterList(self *object.Builtin, args []object.Object) ([]string, *object.Error) {
+	switch fn := args[0].(type) {
+	case *object.Function:
+		params := make([]string, 0, len(fn.Parameters)+1)
+		for _, param := range fn.Parameters {
+			params = append(params, param.String())
+		}
+		if fn.Rest != nil {
+			params = append(params, "..."+fn.Rest.String())
+		}
+		return params, nil
+	case *object.Builtin:
+		return fn.Parameters, nil
+	default:
+		return nil, argTypeError(self, 0, functionType, args[0])
+	}
+}
+
+// builtinSource returns the source of a Monke function. Builtins have none.
+func builtinSource(self *object.Builtin, args []object.Object) object.Object {
+	fn, ok := args[0].(*object.Function)
+	if !ok {
+		return argTypeError(self, 0, object.FUNCTION_OBJ, args[0])
+	}
+	return &object.String{Value: fn.Source()}
+}
+
+// builtinLocals returns the names bound in the scope it is called from.
+func builtinLocals(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+	if err := checkArgCount(args, 0, 0); err != nil {
+		return err
+	}
+	if e.caller == nil {
+		return newHash(nil)
+	}
+	return environmentHash(e.caller)
+}
+
+// builtinGlobals returns the names bound in the global environment of the
+// scope it is called from: the outermost environment that is not frozen,
+// along with the frozen ones enclosing it, such as a shared prelude.
+func builtinGlobals(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+	if err := checkArgCount(args, 0, 0); err != nil {
+		return err
+	}
+	if e.caller == nil {
+		return newHash(nil)
+	}
+	var chain []*object.Environment
+	for env := e.caller; env != nil; env = env.Outer() {
+		chain = append(chain, env)
+	}
+	global := len(chain) - 1
+	for global > 0 && chain[global].Frozen() {
+		global--
+	}
+	return environmentHash(chain[global:]...)
+}
+
+// environmentHash returns a hash of the names bound in envs, where names in
+// earlier environments shadow those in later ones.
+func environmentHash(envs ...*object.Environment) *object.Hash {
+	bindings := make(map[string]object.Object)
+	for i := len(envs) - 1; i >= 0; i-- {
+		for _, name := range envs[i].Names() {
+			bindings[name], _ = envs[i].Get(name)
+		}
+	}
+	return newHash(bindings)
+}
@@ -0,0 +1,133 @@
+package evaluator_test
+
+import (
+	"github.com/muter3000/monkeparser/pkg/evaluator"
+	"github.com/muter3000/monkeparser/pkg/lexer"
+	"github.com/muter3000/monkeparser/pkg/object"
+	"github.com/muter3000/monkeparser/pkg/parser"
+	"testing"
+)
+
+func TestReflectionBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`map([1, 1.5, "s", true, null, [], {}, fn() {}, len], type)`,
+			"[INTEGER, FLOAT, STRING, BOOLEAN, NULL, ARRAY, HASH, FUNCTION, BUILTIN]"},
+		{`import "re" as re; [type(re), type(re.compile("a"))]`, "[MODULE, REGEX]"},
+		{`inspect([1, "a", {"k": 2.0}])`, "[1, a, {k: 2.0}]"},
+		{`len(inspect(12345))`, "5"},
+		{`map([fn() {}, len, 1, "len"], is_callable)`, "[true, true, false, false]"},
+		{`arity(fn(a, b) { a })`, "2"},
+		{`arity(fn(a, scale = 1, ...rest) { a })`, "2"},
+		{`arity(push)`, "2"},
+		{`import "math" as math; arity(math.min)`, "null"},
+		{`params(fn(a, {name}, scale = 1, ...rest) { a })`, "[a, {name}, scale = 1, ...rest]"},
+		{`params(fn() { 1 })`, "[]"},
+		{`params(push)`, "[array, value]"},
+		{`source(fn(x, y = 2) { x + y })`, "fn(x, y = 2){ (x + y); }"},
+		{`let add = fn(x) { fn(y) { x + y } }; source(add(1))`, "fn(y){ (x + y); }"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. want=%s, got=%+v", tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+func TestReflectionBuiltinErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`type()`, "wrong number of arguments. got=0, want=1"},
+		{`arity(1)`, "argument `func` to `arity` must be FUNCTION or BUILTIN, got INTEGER"},
+		{`params("f")`, "argument `func` to `params` must be FUNCTION or BUILTIN, got STRING"},
+		{`source(len)`, "argument `func` to `source` must be FUNCTION, got BUILTIN"},
+		{`locals(1)`, "wrong number of arguments. got=1, want=0"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("object is not Error for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		}
+	}
+}
+
+func TestGlobalsAndLocals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let a = 1; let b = "x"; globals()`, "{a: 1, b: x}"},
+		{`let a = 1; locals()`, "{a: 1}"},
+		{`let a = 1; let f = fn(x) { let y = x * 2; locals() }; f(3)`, "{x: 3, y: 6}"},
+		{`let a = 1; let f = fn(x) { let a = 2; globals().a }; f(3)`, "1"},
+		{`let f = fn(x) { fn(y) { locals() } }; f(1)(2)`, "{y: 2}"},
+		{`let f = fn(x) { fn(y) { len(globals()) } }; f(1)(2)`, "1"},
+		{`let a = 1; let f = fn() { match (a) { 1 => locals() } }; f()`, "{}"},
+		{`let f = fn(n) { if (n == 0) { locals() } else { f(n - 1) } }; f(3)`, "{n: 0}"},
+		{`let x = 1; map([1], fn(v) { locals() })`, "[{v: 1}]"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. want=%s, got=%+v", tt.input, tt.expected, evaluated)
+		}
+	}
+
+	prelude := object.NewEnvironment()
+	prelude.Set("shared", &object.Integer{Value: 1})
+	prelude.Freeze()
+	env := object.NewEnclosedEnvironment(prelude)
+	program := parser.New(lexer.New(`let own = 2; let f = fn() { globals() }; [locals().own, f().shared, f().own]`)).ParseProgram()
+	evaluated := evaluator.Eval(program, env)
+	if evaluated.Inspect() != "[2, 1, 2]" {
+		t.Errorf("wrong globals with a prelude. got=%s", evaluated.Inspect())
+	}
+}
+
+func TestSourceParsesBack(t *testing.T) {
+	tests := []struct {
+		function string
+		args     string
+	}{
+		{`fn(x, y = 2) { x + y }`, `1`},
+		{`fn(x) { let s = if (x < 0) { "-" } else { "+" }; s + "\"q\"\n" }`, `-1`},
+		{`fn(x) { (if (x) { 1 } else { 2 }) * 10 + if (!x) { 3 } }`, `false`},
+		{`fn(x) { (x ?? 0) > 1 ? "big" : "small" }`, `null`},
+		{`fn({name}, [first, ..rest], ...more) { [name, first, rest, more] }`, `{"name": "ana"}, [1, 2, 3], 4, 5`},
+		{`fn(h) { [h?.a?.b, h?["k"], h.list[1]] }`, `{"list": [1, 2.5]}`},
+		{`fn(x) { match (x) { [a, ..b] => b, {"tags": [first, .._]} => first, n if n > 10 => "big", null => 0, _ => -1.5 } }`, `{"tags": [1]}`},
+		{`fn(x) { match (x) { -1 => "int", -1.5 => "float", _ => "other" } }`, `-1.5`},
+		{`fn(x) { try { if (x) { throw "boom" } ; 1 } catch (e) { e } finally { 2 } }`, `true`},
+		{`fn(n) { let loop = fn(i, acc) { if (i == 0) { return acc; } loop(i - 1, acc * i) }; loop(n, 1) }`, `5`},
+		{`fn(xs) { let g = fn(x, scale = 1) { x * scale }; [map(xs, g), g(2, scale: 3)] }`, `[1, 2]`},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(`let f = ` + tt.function + `; [source(f), f(` + tt.args + `)]`)
+		result, ok := evaluated.(*object.Array)
+		if !ok {
+			t.Errorf("wrong result for %s. got=%+v", tt.function, evaluated)
+			continue
+		}
+		source := result.Elements[0].Inspect()
+		p := parser.New(lexer.New(`let f = ` + source + `; [source(f), f(` + tt.args + `)]`))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Errorf("source of %s does not parse: %s: %v", tt.function, source, p.Errors())
+			continue
+		}
+		reparsed := evaluator.Eval(program, object.NewEnvironment())
+		if reparsed == nil || reparsed.Inspect() != result.Inspect() {
+			t.Errorf("source of %s behaves differently. want=%s, got=%+v", tt.function, result.Inspect(), reparsed)
+		}
+	}
+}
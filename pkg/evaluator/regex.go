@@ -0,0 +1,175 @@
+package evaluator
+
+import (
+	"errors"
+	"github.com/muter3000/monkeparser/pkg/object"
+	"regexp"
+	"regexp/syntax"
+	"unicode/utf8"
+)
+
+// newRegexModule creates the re module, which uses Go's RE2 syntax. Every
+// function takes either a regex from re.compile or a pattern string, which is
+// compiled on each call. Match offsets count runes, like the strings module.
+func newRegexModule() *object.Module {
+	return newNativeModule("re",
+		nativeFunction("re.compile", []string{"pattern"}, 1, func(self *object.Builtin, args []object.Object) object.Object {
+			re, err := regexArg(self, args, 0)
+			if err != nil {
+				return err
+			}
+			return &object.Regex{Value: re}
+		}),
+		nativeFunction("re.match", []string{"pattern", "s"}, 2, func(self *object.Builtin, args []object.Object) object.Object {
+			re, s, err := regexAndStringArgs(self, args)
+			if err != nil {
+				return err
+			}
+			return nativeBoolToBooleanObject(re.MatchString(s))
+		}),
+		nativeFunction("re.find", []string{"pattern", "s"}, 2, func(self *object.Builtin, args []object.Object) object.Object {
+			re, s, err := regexAndStringArgs(self, args)
+			if err != nil {
+				return err
+			}
+			match := re.FindStringSubmatchIndex(s)
+			if match == nil {
+				return NULL
+			}
+			return matchHash(re, s, match)
+		}),
+		nativeFunction("re.find_all", []string{"pattern", "s", "count"}, 2, func(self *object.Builtin, args []object.Object) object.Object {
+			re, s, err := regexAndStringArgs(self, args)
+			if err != nil {
+				return err
+			}
+			count, err := optionalCount(self, args, 2)
+			if err != nil {
+				return err
+			}
+			matches := re.FindAllStringSubmatchIndex(s, count)
+			elements := make([]object.Object, len(matches))
+			for i, match := range matches {
+				elements[i] = matchHash(re, s, match)
+			}
+			return &object.Array{Elements: elements}
+		}),
+		nativeEvaluatorFunction("re.replace", []string{"pattern", "s", "replacement", "count"}, 3, func(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+			re, s, err := regexAndStringArgs(self, args)
+			if err != nil {
+				return err
+			}
+			replacement, err := stringArg(self, args, 2)
+			if err != nil {
+				return err
+			}
+			count, err := optionalCount(self, args, 3)
+			if err != nil {
+				return err
+			}
+			var result []byte
+			last := 0
+			for _, match := range re.FindAllStringSubmatchIndex(s, count) {
+				result = append(result, s[last:match[0]]...)
+				result = re.ExpandString(result, replacement, s, match)
+				last = match[1]
+				if err := e.checkAllocation(int64(len(result) + len(s) - last)); err != nil {
+					return err
+				}
+			}
+			result = append(result, s[last:]...)
+			return &object.String{Value: string(result)}
+		}),
+		nativeFunction("re.split", []string{"pattern", "s", "count"}, 2, func(self *object.Builtin, args []object.Object) object.Object {
+			re, s, err := regexAndStringArgs(self, args)
+			if err != nil {
+				return err
+			}
+			count, err := optionalCount(self, args, 2)
+			if err != nil {
+				return err
+			}
+			return stringArray(re.Split(s, count))
+		}),
+	)
+}
+
+// patternType names the accepted types in errors for pattern arguments.
+const patternType = object.REGEX_OBJ + " or " + object.STRING_OBJ
+
+// regexArg returns argument i of a call to fn as a compiled regex. Invalid
+// patterns are argument errors rather than panics, so scripts can catch them.
+func regexArg(fn *object.Builtin, args []object.Object, i int) (*regexp.Regexp, *object.Error) {
+	switch arg := args[i].(type) {
+	case *object.Regex:
+		return arg.Value, nil
+	case *object.String:
+		re, err := regexp.Compile(arg.Value)
+		if err != nil {
+			var syntaxErr *syntax.Error
+			if errors.As(err, &syntaxErr) {
+				return nil, newError(object.ArgumentError, "invalid pattern %q in call to %s: %s", arg.Value, fn.Name, syntaxErr.Code)
+			}
+			return nil, newError(object.ArgumentError, "invalid pattern %q in call to %s: %s", arg.Value, fn.Name, err)
+		}
+		return re, nil
+	default:
+		return nil, argTypeError(fn, i, patternType, args[i])
+	}
+}
+
+func regexAndStringArgs(fn *object.Builtin, args []object.Object) (*regexp.Regexp, string, *object.Error) {
+	re, err := regexArg(fn, args, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	s, err := stringArg(fn, args, 1)
+	if err != nil {
+		return nil, "", err
+	}
+	return re, s, nil
+}
+
+// optionalCount returns the optional count argument at i, which limits how
+// many matches a function uses. It defaults to -1, meaning all of them.
+func optionalCount(fn *object.Builtin, args []object.Object, i int) (int, *object.Error) {
+	if len(args) <= i {
+		return -1, nil
+	}
+	count, err := integerArg(fn, args, i)
+	if err != nil {
+		return 0, err
+	}
+	if count < 0 {
+		return -1, nil
+	}
+	return int(count), nil
+}
+
+// matchHash describes a match given as submatch byte offsets into s. Its
+// groups are the capturing groups in order and named maps the named ones;
+// groups that did not take part in the match are null.
+func matchHash(re *regexp.Regexp, s string, match []int) *object.Hash {
+	group := func(i int) object.Object {
+		if match[2*i] < 0 {
+			return NULL
+		}
+		return &object.String{Value: s[match[2*i]:match[2*i+1]]}
+	}
+	groups := make([]object.Object, re.NumSubexp())
+	named := make(map[string]object.Object)
+	for i, name := range re.SubexpNames()[1:] {
+		groups[i] = group(i + 1)
+		if name != "" {
+			named[name] = groups[i]
+		}
+	}
+	start := utf8.RuneCountInString(s[:match[0]])
+	return newHash(map[string]object.Object{
+		"text":   group(0),
+		"start":  &object.Integer{Value: int64(start)},
+		"end":    &object.Integer{Value: int64(start + utf8.RuneCountInString(s[match[0]:match[1]]))},
+		"groups": &object.Array{Elements: groups},
+		"named":  newHash(named),
+	})
+}
@@ -0,0 +1,57 @@
+package evaluator_test
+
+import (
+	"testing"
+)
+
+func TestRegexCompile(t *testing.T) {
+	testModule(t, "re", []moduleTest{
+		{`re.compile("a+b")`, inspected(`<regex "a+b">`)},
+		{`let digits = re.compile("[0-9]+"); [re.match(digits, "a1"), re.match(digits, "ab")]`, inspected("[true, false]")},
+		{`re.compile("(")`, errorMessage("invalid pattern \"(\" in call to re.compile: missing closing )")},
+		{`re.match("[a", "a")`, errorMessage("invalid pattern \"[a\" in call to re.match: missing closing ]")},
+		{`try { re.compile("*") } catch (e) { e.kind }`, "ArgumentError"},
+		{`re.compile(1)`, errorMessage("argument `pattern` to `re.compile` must be REGEX or STRING, got INTEGER")},
+		{`re.match("a", 1)`, errorMessage("argument `s` to `re.match` must be STRING, got INTEGER")},
+	})
+}
+
+func TestRegexFind(t *testing.T) {
+	testModule(t, "re", []moduleTest{
+		{`re.find("b+", "abbc").text`, "bb"},
+		{`re.find("b+", "abbc").start`, 1},
+		{`re.find("b+", "abbc").end`, 3},
+		{`re.find("b+", "xyz")`, nil},
+		{`re.find("é(.)", "aéb").start`, 1},
+		{`re.find("(\\w+)@(\\w+)", "mail ana@example now").groups`, []string{"ana", "example"}},
+		{`re.find("(?P<user>\\w+)@(?P<host>\\w+)", "ana@example").named.host`, "example"},
+		{`re.find("(a)|(b)", "b").groups`, inspected("[null, b]")},
+		{`re.find("(?P<a>a)|(?P<b>b)", "b").named`, inspected("{a: null, b: b}")},
+		{`len(re.find_all("[0-9]+", "1 22 333"))`, 3},
+		{`re.find_all("[0-9]+", "1 22 333")[2].text`, "333"},
+		{`len(re.find_all("[0-9]+", "1 22 333", 2))`, 2},
+		{`re.find_all("x", "abc")`, inspected("[]")},
+	})
+}
+
+func TestRegexReplace(t *testing.T) {
+	testModule(t, "re", []moduleTest{
+		{`re.replace("o+", "foo boo", "0")`, "f0 b0"},
+		{`re.replace("(\\w+)@(\\w+)", "ana@example", "$2 at ${1}")`, "example at ana"},
+		{`re.replace("(?P<first>\\w+) (?P<last>\\w+)", "Ana Novak", "${last}, ${first}")`, "Novak, Ana"},
+		{`re.replace("a", "aaa", "b", 2)`, "bba"},
+		{`re.replace("x*", "abc", "-")`, "-a-b-c-"},
+		{`re.replace("\\$", "$5", "$$")`, "$5"},
+		{`re.replace(re.compile("[aeiou]"), "monke", "")`, "mnk"},
+		{`re.replace("a", "a", 1)`, errorMessage("argument `replacement` to `re.replace` must be STRING, got INTEGER")},
+	})
+}
+
+func TestRegexSplit(t *testing.T) {
+	testModule(t, "re", []moduleTest{
+		{`re.split(",\\s*", "a, b,c")`, []string{"a", "b", "c"}},
+		{`re.split(",", "a,b,c", 2)`, []string{"a", "b,c"}},
+		{`re.split("x", "abc")`, []string{"abc"}},
+		{`re.split("[", "abc")`, errorMessage("invalid pattern \"[\" in call to re.split: missing closing ]")},
+	})
+}
@@ -0,0 +1,107 @@
+package evaluator
+
+import (
+	"fmt"
+	"github.com/muter3000/monkeparser/pkg/object"
+	"strings"
+)
+
+// stdlib holds the native modules that scripts import by name, as in
+// import "strings" as strings. They take precedence over module files of
+// the same name. The modules are shared by all evaluators; functions that
+// need the configuration or limits of an evaluator are created with
+// nativeEvaluatorFunction and get the one calling them.
+var stdlib = map[string]*object.Module{
+	"strings": newStringsModule(),
+	"math":    newMathModule(),
+	"json":    newJSONModule(),
+	"time":    newTimeModule(),
+	"random":  newRandomModule(),
+	"re":      newRegexModule(),
+}
+
+// nativeModule returns the named native module, if there is one.
+func (e *Evaluator) nativeModule(name string) (*object.Module, bool) {
+	mod, ok := stdlib[name]
+	return mod, ok
+}
+
+// newNativeModule creates a module exporting the given builtins, which must
+// be named after the module, as in strings.split.
+func newNativeModule(name string, builtins ...*object.Builtin) *object.Module {
+	mod := &object.Module{Name: name, Exports: make(map[string]object.Object, len(builtins))}
+	for _, builtin := range builtins {
+		mod.Exports[strings.TrimPrefix(builtin.Name, name+".")] = builtin
+	}
+	return mod
+}
+
+// nativeFunction creates a builtin whose first required parameters must be
+// given; the rest are optional. fn receives the builtin itself for error
+// messages.
+func nativeFunction(name string, params []string, required int, fn func(self *object.Builtin, args []object.Object) object.Object) *object.Builtin {
+	builtin := &object.Builtin{Name: name, Parameters: params}
+	builtin.Fn = func(args ...object.Object) object.Object {
+		if err := checkArgCount(args, required, len(params)); err != nil {
+			return err
+		}
+		return fn(builtin, args)
+	}
+	return builtin
+}
+
+// nativeEvaluatorFunction is like nativeFunction for functions that need the
+// evaluator calling them, as withEvaluator does for builtins.
+func nativeEvaluatorFunction(name string, params []string, required int, fn evaluatorBuiltin) *object.Builtin {
+	return withEvaluator(name, params, func(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+		if err := checkArgCount(args, required, len(params)); err != nil {
+			return err
+		}
+		return fn(e, self, args)
+	})
+}
+
+// checkArgCount reports calls to a builtin with fewer than min or more than
+// max arguments.
+func checkArgCount(args []object.Object, min, max int) *object.Error {
+	if len(args) >= min && len(args) <= max {
+		return nil
+	}
+	want := fmt.Sprint(min)
+	if max > min {
+		want = fmt.Sprintf("%d..%d", min, max)
+	}
+	return newError(object.ArgumentError, "wrong number of arguments. got=%d, want=%s", len(args), want)
+}
+
+func argTypeError(fn *object.Builtin, i int, expected object.ObjectType, got object.Object) *object.Error {
+	return newError(object.TypeError, "argument `%s` to `%s` must be %s, got %s",
+		fn.Parameters[i], fn.Name, expected, got.Type())
+}
+
+// stringArg returns argument i of a call to fn as a string.
+func stringArg(fn *object.Builtin, args []object.Object, i int) (string, *object.Error) {
+	str, ok := args[i].(*object.String)
+	if !ok {
+		return "", argTypeError(fn, i, object.STRING_OBJ, args[i])
+	}
+	return str.Value, nil
+}
+
+// integerArg returns argument i of a call to fn as an integer.
+func integerArg(fn *object.Builtin, args []object.Object, i int) (int64, *object.Error) {
+	integer, ok := args[i].(*object.Integer)
+	if !ok {
+		return 0, argTypeError(fn, i, object.INTEGER_OBJ, args[i])
+	}
+	return integer.Value, nil
+}
+
+// checkAllocation fails before a builtin allocates size bytes that would go
+// over the memory limit, so that one call cannot exhaust the host.
+func (e *Evaluator) checkAllocation(size int64) *object.Error {
+	if e.MaxMemory > 0 && e.memory+size > e.MaxMemory {
+		return limitError("memory limit of %d bytes exceeded", e.MaxMemory)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package evaluator_test
+
+import (
+	"fmt"
+	"github.com/muter3000/monkeparser/pkg/evaluator"
+	"github.com/muter3000/monkeparser/pkg/lexer"
+	"github.com/muter3000/monkeparser/pkg/object"
+	"github.com/muter3000/monkeparser/pkg/parser"
+	"math"
+	"testing"
+	"time"
+)
+
+type moduleTest struct {
+	input    string
+	expected interface{} // nil, int, float64, bool, string, []string, inspected, or errorMessage
+}
+
+type errorMessage string
+
+// inspected is the expected Inspect output of a value.
+type inspected string
+
+// testModule evaluates the inputs with the named native module imported
+// under its own name.
+func testModule(t *testing.T, name string, tests []moduleTest) {
+	t.Helper()
+	testModuleWith(t, evaluator.New, name, tests)
+}
+
+// testModuleWith is testModule with evaluators created by newEvaluator.
+func testModuleWith(t *testing.T, newEvaluator func() *evaluator.Evaluator, name string, tests []moduleTest) {
+	t.Helper()
+	for _, tt := range tests {
+		input := fmt.Sprintf(`import %q as %s; %s`, name, name, tt.input)
+		p := parser.New(lexer.New(input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Errorf("parser errors for %q: %v", tt.input, p.Errors())
+			continue
+		}
+		evaluated := newEvaluator().Eval(program, object.NewEnvironment())
+		switch expected := tt.expected.(type) {
+		case nil:
+			testNullObject(t, evaluated)
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case float64:
+			float, ok := evaluated.(*object.Float)
+			if !ok {
+				t.Errorf("object is not Float for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if math.Abs(float.Value-expected) > 1e-9 {
+				t.Errorf("wrong value for %q. want=%g, got=%g", tt.input, expected, float.Value)
+			}
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			testStringObject(t, evaluated, expected)
+		case []string:
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("object is not Array for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if len(array.Elements) != len(expected) {
+				t.Errorf("wrong number of elements for %q. want=%d, got=%d", tt.input, len(expected), len(array.Elements))
+				continue
+			}
+			for i, el := range expected {
+				testStringObject(t, array.Elements[i], el)
+			}
+		case inspected:
+			if evaluated == nil || evaluated.Inspect() != string(expected) {
+				t.Errorf("wrong value for %q. want=%s, got=%+v", tt.input, expected, evaluated)
+			}
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestNativeModulesUseCallingEvaluator(t *testing.T) {
+	env := object.NewEnvironment()
+	first := evaluator.New()
+	first.MaxMemory = 100
+	first.Clock = func() time.Time { return time.UnixMilli(1000) }
+	first.Eval(parser.New(lexer.New(`import "strings" as strings; import "time" as time`)).ParseProgram(), env)
+
+	second := evaluator.New()
+	second.Clock = func() time.Time { return time.UnixMilli(2000) }
+	evaluated := second.Eval(parser.New(lexer.New(`len(strings.repeat("ab", 1000))`)).ParseProgram(), env)
+	testIntegerObject(t, evaluated, 2000)
+	evaluated = second.Eval(parser.New(lexer.New(`time.now()`)).ParseProgram(), env)
+	testIntegerObject(t, evaluated, 2000)
+}
@@ -0,0 +1,251 @@
+package evaluator
+
+import (
+	"github.com/muter3000/monkeparser/pkg/object"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// newStringsModule creates the strings module. Lengths and indexes count
+// runes rather than bytes, so that they agree with chars.
+func newStringsModule() *object.Module {
+	return newNativeModule("strings",
+		nativeFunction("strings.split", []string{"s", "sep"}, 2, func(self *object.Builtin, args []object.Object) object.Object {
+			s, sep, err := twoStringArgs(self, args)
+			if err != nil {
+				return err
+			}
+			return stringArray(strings.Split(s, sep))
+		}),
+		nativeFunction("strings.join", []string{"parts", "sep"}, 2, func(self *object.Builtin, args []object.Object) object.Object {
+			array, ok := args[0].(*object.Array)
+			if !ok {
+				return argTypeError(self, 0, object.ARRAY_OBJ, args[0])
+			}
+			sep, err := stringArg(self, args, 1)
+			if err != nil {
+				return err
+			}
+			parts := make([]string, len(array.Elements))
+			for i, el := range array.Elements {
+				str, ok := el.(*object.String)
+				if !ok {
+					return newError(object.TypeError, "element %d of `parts` to `%s` must be STRING, got %s", i, self.Name, el.Type())
+				}
+				parts[i] = str.Value
+			}
+			return &object.String{Value: strings.Join(parts, sep)}
+		}),
+		nativeFunction("strings.trim", []string{"s", "cutset"}, 1, func(self *object.Builtin, args []object.Object) object.Object {
+			s, err := stringArg(self, args, 0)
+			if err != nil {
+				return err
+			}
+			if len(args) == 1 {
+				return &object.String{Value: strings.TrimSpace(s)}
+			}
+			cutset, err := stringArg(self, args, 1)
+			if err != nil {
+				return err
+			}
+			return &object.String{Value: strings.Trim(s, cutset)}
+		}),
+		stringMapper("strings.upper", strings.ToUpper),
+		stringMapper("strings.lower", strings.ToLower),
+		stringPredicate("strings.contains", "substr", strings.Contains),
+		stringPredicate("strings.starts_with", "prefix", strings.HasPrefix),
+		stringPredicate("strings.ends_with", "suffix", strings.HasSuffix),
+		nativeEvaluatorFunction("strings.replace", []string{"s", "old", "new", "count"}, 3, func(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+			var parts [3]string
+			for i := range parts {
+				part, err := stringArg(self, args, i)
+				if err != nil {
+					return err
+				}
+				parts[i] = part
+			}
+			count := int64(-1)
+			if len(args) == 4 {
+				var err *object.Error
+				if count, err = integerArg(self, args, 3); err != nil {
+					return err
+				}
+			}
+			s, old, replacement := parts[0], parts[1], parts[2]
+			if n := int64(strings.Count(s, old)); count < 0 || count > n {
+				count = n
+			}
+			size := int64(len(s)) + count*(int64(len(replacement))-int64(len(old)))
+			if err := e.checkAllocation(size); err != nil {
+				return err
+			}
+			return &object.String{Value: strings.Replace(s, old, replacement, int(count))}
+		}),
+		nativeFunction("strings.index_of", []string{"s", "substr"}, 2, func(self *object.Builtin, args []object.Object) object.Object {
+			s, substr, err := twoStringArgs(self, args)
+			if err != nil {
+				return err
+			}
+			i := strings.Index(s, substr)
+			if i >= 0 {
+				i = utf8.RuneCountInString(s[:i])
+			}
+			return &object.Integer{Value: int64(i)}
+		}),
+		nativeEvaluatorFunction("strings.repeat", []string{"s", "count"}, 2, func(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+			s, err := stringArg(self, args, 0)
+			if err != nil {
+				return err
+			}
+			count, err := integerArg(self, args, 1)
+			if err != nil {
+				return err
+			}
+			if count < 0 {
+				return newError(object.ArgumentError, "negative count %d in call to %s", count, self.Name)
+			}
+			if err := e.checkLength(self, int64(len(s)), count); err != nil {
+				return err
+			}
+			return &object.String{Value: strings.Repeat(s, int(count))}
+		}),
+		padFunction("strings.pad_left", true),
+		padFunction("strings.pad_right", false),
+		nativeFunction("strings.chars", []string{"s"}, 1, func(self *object.Builtin, args []object.Object) object.Object {
+			s, err := stringArg(self, args, 0)
+			if err != nil {
+				return err
+			}
+			chars := make([]string, 0, utf8.RuneCountInString(s))
+			for _, r := range s {
+				chars = append(chars, string(r))
+			}
+			return stringArray(chars)
+		}),
+		nativeFunction("strings.len", []string{"s"}, 1, func(self *object.Builtin, args []object.Object) object.Object {
+			s, err := stringArg(self, args, 0)
+			if err != nil {
+				return err
+			}
+			return &object.Integer{Value: int64(utf8.RuneCountInString(s))}
+		}),
+		nativeFunction("strings.substr", []string{"s", "start", "length"}, 2, func(self *object.Builtin, args []object.Object) object.Object {
+			s, err := stringArg(self, args, 0)
+			if err != nil {
+				return err
+			}
+			start, err := integerArg(self, args, 1)
+			if err != nil {
+				return err
+			}
+			runes := []rune(s)
+			if start < 0 || start > int64(len(runes)) {
+				return newError(object.ArgumentError, "start %d out of range for string of length %d", start, len(runes))
+			}
+			end := int64(len(runes))
+			if len(args) == 3 {
+				length, err := integerArg(self, args, 2)
+				if err != nil {
+					return err
+				}
+				if length < 0 {
+					return newError(object.ArgumentError, "negative length %d in call to %s", length, self.Name)
+				}
+				if length < end-start {
+					end = start + length
+				}
+			}
+			return &object.String{Value: string(runes[start:end])}
+		}),
+	)
+}
+
+func twoStringArgs(fn *object.Builtin, args []object.Object) (string, string, *object.Error) {
+	a, err := stringArg(fn, args, 0)
+	if err != nil {
+		return "", "", err
+	}
+	b, err := stringArg(fn, args, 1)
+	if err != nil {
+		return "", "", err
+	}
+	return a, b, nil
+}
+
+func stringArray(values []string) *object.Array {
+	elements := make([]object.Object, len(values))
+	for i, value := range values {
+		elements[i] = &object.String{Value: value}
+	}
+	return &object.Array{Elements: elements}
+}
+
+func stringMapper(name string, fn func(string) string) *object.Builtin {
+	return nativeFunction(name, []string{"s"}, 1, func(self *object.Builtin, args []object.Object) object.Object {
+		s, err := stringArg(self, args, 0)
+		if err != nil {
+			return err
+		}
+		return &object.String{Value: fn(s)}
+	})
+}
+
+func stringPredicate(name string, param string, fn func(string, string) bool) *object.Builtin {
+	return nativeFunction(name, []string{"s", param}, 2, func(self *object.Builtin, args []object.Object) object.Object {
+		s, arg, err := twoStringArgs(self, args)
+		if err != nil {
+			return err
+		}
+		return nativeBoolToBooleanObject(fn(s, arg))
+	})
+}
+
+// padFunction pads strings to a width in runes with copies of pad, the last
+// of which is cut short if needed.
+func padFunction(name string, left bool) *object.Builtin {
+	return nativeEvaluatorFunction(name, []string{"s", "width", "pad"}, 2, func(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+		s, err := stringArg(self, args, 0)
+		if err != nil {
+			return err
+		}
+		width, err := integerArg(self, args, 1)
+		if err != nil {
+			return err
+		}
+		pad := " "
+		if len(args) == 3 {
+			if pad, err = stringArg(self, args, 2); err != nil {
+				return err
+			}
+			if pad == "" {
+				return newError(object.ArgumentError, "empty pad in call to %s", self.Name)
+			}
+		}
+		missing := width - int64(utf8.RuneCountInString(s))
+		if missing <= 0 {
+			return &object.String{Value: s}
+		}
+		if err := e.checkLength(self, int64(utf8.UTFMax), missing); err != nil {
+			return err
+		}
+		padRunes := []rune(pad)
+		var padding strings.Builder
+		for i := int64(0); i < missing; i++ {
+			padding.WriteRune(padRunes[i%int64(len(padRunes))])
+		}
+		if left {
+			return &object.String{Value: padding.String() + s}
+		}
+		return &object.String{Value: s + padding.String()}
+	})
+}
+
+// checkLength fails calls to fn that would build a string of count pieces
+// of size bytes each that is too large to allocate.
+func (e *Evaluator) checkLength(fn *object.Builtin, size, count int64) *object.Error {
+	if size > 0 && count > math.MaxInt32/size {
+		return newError(object.ArgumentError, "result of %s too large", fn.Name)
+	}
+	return e.checkAllocation(size * count)
+}
@@ -0,0 +1,168 @@
+package evaluator_test
+
+import (
+	"github.com/muter3000/monkeparser/pkg/evaluator"
+	"github.com/muter3000/monkeparser/pkg/lexer"
+	"github.com/muter3000/monkeparser/pkg/object"
+	"github.com/muter3000/monkeparser/pkg/parser"
+	"testing"
+)
+
+func TestStringsSplit(t *testing.T) {
+	testModule(t, "strings", []moduleTest{
+		{`strings.split("a,b,c", ",")`, []string{"a", "b", "c"}},
+		{`strings.split("abc", "")`, []string{"a", "b", "c"}},
+		{`strings.split("", ",")`, []string{""}},
+		{`strings.split(sep: "-", s: "x-y")`, []string{"x", "y"}},
+		{`strings.split("a")`, errorMessage("wrong number of arguments. got=1, want=2")},
+		{`strings.split(1, ",")`, errorMessage("argument `s` to `strings.split` must be STRING, got INTEGER")},
+		{`strings.split("a", 1)`, errorMessage("argument `sep` to `strings.split` must be STRING, got INTEGER")},
+	})
+}
+
+func TestStringsJoin(t *testing.T) {
+	testModule(t, "strings", []moduleTest{
+		{`strings.join(["a", "b", "c"], ", ")`, "a, b, c"},
+		{`strings.join([], ",")`, ""},
+		{`strings.join("abc", ",")`, errorMessage("argument `parts` to `strings.join` must be ARRAY, got STRING")},
+		{`strings.join(["a", 1], ",")`, errorMessage("element 1 of `parts` to `strings.join` must be STRING, got INTEGER")},
+	})
+}
+
+func TestStringsTrim(t *testing.T) {
+	testModule(t, "strings", []moduleTest{
+		{`strings.trim("  a b \t")`, "a b"},
+		{`strings.trim("xxaxx", "x")`, "a"},
+		{`strings.trim(1)`, errorMessage("argument `s` to `strings.trim` must be STRING, got INTEGER")},
+		{`strings.trim("a", "b", "c")`, errorMessage("wrong number of arguments. got=3, want=1..2")},
+	})
+}
+
+func TestStringsUpper(t *testing.T) {
+	testModule(t, "strings", []moduleTest{
+		{`strings.upper("Hello, wörld")`, "HELLO, WÖRLD"},
+		{`strings.upper(true)`, errorMessage("argument `s` to `strings.upper` must be STRING, got BOOLEAN")},
+	})
+}
+
+func TestStringsLower(t *testing.T) {
+	testModule(t, "strings", []moduleTest{
+		{`strings.lower("Hello, WÖRLD")`, "hello, wörld"},
+		{`strings.lower()`, errorMessage("wrong number of arguments. got=0, want=1")},
+	})
+}
+
+func TestStringsContains(t *testing.T) {
+	testModule(t, "strings", []moduleTest{
+		{`strings.contains("monkey", "key")`, true},
+		{`strings.contains("monkey", "")`, true},
+		{`strings.contains("monkey", "donkey")`, false},
+		{`strings.contains("monkey", null)`, errorMessage("argument `substr` to `strings.contains` must be STRING, got NULL")},
+	})
+}
+
+func TestStringsStartsWith(t *testing.T) {
+	testModule(t, "strings", []moduleTest{
+		{`strings.starts_with("monkey", "mon")`, true},
+		{`strings.starts_with("monkey", "key")`, false},
+		{`strings.starts_with("monkey", 1)`, errorMessage("argument `prefix` to `strings.starts_with` must be STRING, got INTEGER")},
+	})
+}
+
+func TestStringsEndsWith(t *testing.T) {
+	testModule(t, "strings", []moduleTest{
+		{`strings.ends_with("monkey", "key")`, true},
+		{`strings.ends_with("monkey", "mon")`, false},
+		{`strings.ends_with("monkey", 1)`, errorMessage("argument `suffix` to `strings.ends_with` must be STRING, got INTEGER")},
+	})
+}
+
+func TestStringsReplace(t *testing.T) {
+	testModule(t, "strings", []moduleTest{
+		{`strings.replace("a-b-c", "-", "+")`, "a+b+c"},
+		{`strings.replace("a-b-c", "-", "+", 1)`, "a+b-c"},
+		{`strings.replace("a-b-c", "-", "+", -1)`, "a+b+c"},
+		{`strings.replace("a-b-c", "-", "", 10)`, "abc"},
+		{`strings.replace("a-b", "-", 1)`, errorMessage("argument `new` to `strings.replace` must be STRING, got INTEGER")},
+		{`strings.replace("a-b", "-", "+", "1")`, errorMessage("argument `count` to `strings.replace` must be INTEGER, got STRING")},
+	})
+}
+
+func TestStringsIndexOf(t *testing.T) {
+	testModule(t, "strings", []moduleTest{
+		{`strings.index_of("monkey", "key")`, 3},
+		{`strings.index_of("größe", "e")`, 4},
+		{`strings.index_of("monkey", "x")`, -1},
+		{`strings.index_of("monkey")`, errorMessage("wrong number of arguments. got=1, want=2")},
+	})
+}
+
+func TestStringsRepeat(t *testing.T) {
+	testModule(t, "strings", []moduleTest{
+		{`strings.repeat("ab", 3)`, "ababab"},
+		{`strings.repeat("ab", 0)`, ""},
+		{`strings.repeat("ab", -1)`, errorMessage("negative count -1 in call to strings.repeat")},
+		{`strings.repeat("ab", 9000000000000000000)`, errorMessage("result of strings.repeat too large")},
+		{`strings.repeat(1, 2)`, errorMessage("argument `s` to `strings.repeat` must be STRING, got INTEGER")},
+	})
+}
+
+func TestStringsPadLeft(t *testing.T) {
+	testModule(t, "strings", []moduleTest{
+		{`strings.pad_left("7", 3)`, "  7"},
+		{`strings.pad_left("7", 3, "0")`, "007"},
+		{`strings.pad_left("7", 6, "ab")`, "ababa7"},
+		{`strings.pad_left("wörd", 5, "·")`, "·wörd"},
+		{`strings.pad_left("long", 2)`, "long"},
+		{`strings.pad_left("7", 3, "")`, errorMessage("empty pad in call to strings.pad_left")},
+		{`strings.pad_left("7", "3")`, errorMessage("argument `width` to `strings.pad_left` must be INTEGER, got STRING")},
+	})
+}
+
+func TestStringsPadRight(t *testing.T) {
+	testModule(t, "strings", []moduleTest{
+		{`strings.pad_right("7", 3)`, "7  "},
+		{`strings.pad_right("7", 4, "ab")`, "7aba"},
+		{`strings.pad_right(pad: ".", width: 3, s: "a")`, "a.."},
+		{`strings.pad_right("7", 9000000000000000000)`, errorMessage("result of strings.pad_right too large")},
+	})
+}
+
+func TestStringsChars(t *testing.T) {
+	testModule(t, "strings", []moduleTest{
+		{`strings.chars("añb")`, []string{"a", "ñ", "b"}},
+		{`strings.chars("")`, []string{}},
+		{`strings.chars([])`, errorMessage("argument `s` to `strings.chars` must be STRING, got ARRAY")},
+	})
+}
+
+func TestStringsLen(t *testing.T) {
+	testModule(t, "strings", []moduleTest{
+		{`strings.len("größe")`, 5},
+		{`len("größe")`, 7},
+		{`strings.len("")`, 0},
+		{`strings.len({})`, errorMessage("argument `s` to `strings.len` must be STRING, got HASH")},
+	})
+}
+
+func TestStringsSubstr(t *testing.T) {
+	testModule(t, "strings", []moduleTest{
+		{`strings.substr("größe", 2)`, "öße"},
+		{`strings.substr("größe", 1, 2)`, "rö"},
+		{`strings.substr("größe", 3, 10)`, "ße"},
+		{`strings.substr("größe", 5)`, ""},
+		{`strings.substr("größe", 6)`, errorMessage("start 6 out of range for string of length 5")},
+		{`strings.substr("größe", -1)`, errorMessage("start -1 out of range for string of length 5")},
+		{`strings.substr("größe", 0, -1)`, errorMessage("negative length -1 in call to strings.substr")},
+	})
+}
+
+func TestStringsModuleLimits(t *testing.T) {
+	e := evaluator.New()
+	e.MaxMemory = 10000
+	evaluated := e.Eval(parser.New(lexer.New(`import "strings" as s; s.repeat("ab", 100000)`)).ParseProgram(), object.NewEnvironment())
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Kind != object.LimitError {
+		t.Fatalf("expected LimitError. got=%T (%+v)", evaluated, evaluated)
+	}
+}
@@ -0,0 +1,111 @@
+package evaluator
+
+import (
+	"github.com/muter3000/monkeparser/pkg/object"
+	"math"
+	"time"
+)
+
+// newTimeModule creates the time module. Times are integers counting
+// milliseconds since the Unix epoch and durations are integers counting
+// milliseconds, so plain arithmetic adds and compares them. Times are
+// formatted and parsed in UTC so that scripts behave the same everywhere.
+func newTimeModule() *object.Module {
+	mod := newNativeModule("time",
+		nativeEvaluatorFunction("time.now", nil, 0, func(e *Evaluator, self *object.Builtin, args []object.Object) object.Object {
+			if e.Clock == nil {
+				return &object.Integer{Value: time.Now().UnixMilli()}
+			}
+			return &object.Integer{Value: e.Clock().UnixMilli()}
+		}),
+		durationFunction("time.seconds", time.Second),
+		durationFunction("time.minutes", time.Minute),
+		durationFunction("time.hours", time.Hour),
+		durationFunction("time.days", 24*time.Hour),
+		nativeFunction("time.format", []string{"time", "layout"}, 2, func(self *object.Builtin, args []object.Object) object.Object {
+			millis, err := integerArg(self, args, 0)
+			if err != nil {
+				return err
+			}
+			layout, err := stringArg(self, args, 1)
+			if err != nil {
+				return err
+			}
+			return &object.String{Value: time.UnixMilli(millis).UTC().Format(layout)}
+		}),
+		nativeFunction("time.parse", []string{"text", "layout"}, 2, func(self *object.Builtin, args []object.Object) object.Object {
+			text, layout, err := twoStringArgs(self, args)
+			if err != nil {
+				return err
+			}
+			t, parseErr := time.Parse(layout, text)
+			if parseErr != nil {
+				return newError(object.ArgumentError, "cannot parse time %q: %s", text, parseErr)
+			}
+			return &object.Integer{Value: t.UnixMilli()}
+		}),
+		nativeFunction("time.duration", []string{"text"}, 1, func(self *object.Builtin, args []object.Object) object.Object {
+			text, err := stringArg(self, args, 0)
+			if err != nil {
+				return err
+			}
+			d, parseErr := time.ParseDuration(text)
+			if parseErr != nil {
+				return newError(object.ArgumentError, "cannot parse duration %q", text)
+			}
+			return &object.Integer{Value: d.Milliseconds()}
+		}),
+		nativeFunction("time.format_duration", []string{"duration"}, 1, func(self *object.Builtin, args []object.Object) object.Object {
+			millis, err := integerArg(self, args, 0)
+			if err != nil {
+				return err
+			}
+			if millis > math.MaxInt64/int64(time.Millisecond) || millis < math.MinInt64/int64(time.Millisecond) {
+				return newError(object.ArgumentError, "duration %d out of range", millis)
+			}
+			return &object.String{Value: (time.Duration(millis) * time.Millisecond).String()}
+		}),
+		nativeFunction("time.parts", []string{"time"}, 1, func(self *object.Builtin, args []object.Object) object.Object {
+			millis, err := integerArg(self, args, 0)
+			if err != nil {
+				return err
+			}
+			t := time.UnixMilli(millis).UTC()
+			return newHash(map[string]object.Object{
+				"year":        &object.Integer{Value: int64(t.Year())},
+				"month":       &object.Integer{Value: int64(t.Month())},
+				"day":         &object.Integer{Value: int64(t.Day())},
+				"hour":        &object.Integer{Value: int64(t.Hour())},
+				"minute":      &object.Integer{Value: int64(t.Minute())},
+				"second":      &object.Integer{Value: int64(t.Second())},
+				"millisecond": &object.Integer{Value: int64(t.Nanosecond() / int(time.Millisecond))},
+				"weekday":     &object.String{Value: t.Weekday().String()},
+			})
+		}),
+	)
+	for name, layout := range map[string]string{
+		"RFC3339":  "2006-01-02T15:04:05.999Z07:00",
+		"DATE":     time.DateOnly,
+		"TIME":     time.TimeOnly,
+		"DATETIME": time.DateTime,
+	} {
+		mod.Exports[name] = &object.String{Value: layout}
+	}
+	return mod
+}
+
+// durationFunction converts a number of units to a duration in
+// milliseconds.
+func durationFunction(name string, unit time.Duration) *object.Builtin {
+	return nativeFunction(name, []string{"n"}, 1, func(self *object.Builtin, args []object.Object) object.Object {
+		n, err := floatArg(self, args, 0)
+		if err != nil {
+			return err
+		}
+		millis := n * float64(unit/time.Millisecond)
+		if millis >= math.MaxInt64 || millis < math.MinInt64 {
+			return newError(object.ArgumentError, "result of %s out of range", self.Name)
+		}
+		return &object.Integer{Value: int64(millis)}
+	})
+}
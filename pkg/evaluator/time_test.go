@@ -0,0 +1,64 @@
+package evaluator_test
+
+import (
+	"github.com/muter3000/monkeparser/pkg/evaluator"
+	"testing"
+	"time"
+)
+
+func fixedClockEvaluator() *evaluator.Evaluator {
+	e := evaluator.New()
+	e.Clock = func() time.Time { return time.Date(2024, 2, 29, 23, 30, 15, 250e6, time.FixedZone("CET", 3600)) }
+	return e
+}
+
+func TestTimeNow(t *testing.T) {
+	testModuleWith(t, fixedClockEvaluator, "time", []moduleTest{
+		{`time.now()`, 1709245815250},
+		{`time.format(time.now(), time.RFC3339)`, "2024-02-29T22:30:15.25Z"},
+		{`time.now(1)`, errorMessage("wrong number of arguments. got=1, want=0")},
+	})
+}
+
+func TestTimeDurations(t *testing.T) {
+	testModuleWith(t, fixedClockEvaluator, "time", []moduleTest{
+		{`time.seconds(1.5)`, 1500},
+		{`time.minutes(2)`, 120000},
+		{`time.hours(1) + time.minutes(30)`, 5400000},
+		{`time.days(1)`, 86400000},
+		{`time.format(time.now() + time.hours(2), time.DATETIME)`, "2024-03-01 00:30:15"},
+		{`time.now() - time.days(1) < time.now()`, true},
+		{`time.duration("1h30m")`, 5400000},
+		{`time.duration("-250ms")`, -250},
+		{`time.duration("soon")`, errorMessage(`cannot parse duration "soon"`)},
+		{`time.format_duration(5400000)`, "1h30m0s"},
+		{`time.format_duration(9223372036854775807)`, errorMessage("duration 9223372036854775807 out of range")},
+		{`time.days(1e300)`, errorMessage("result of time.days out of range")},
+		{`time.seconds("1")`, errorMessage("argument `n` to `time.seconds` must be INTEGER or FLOAT, got STRING")},
+	})
+}
+
+func TestTimeFormatAndParse(t *testing.T) {
+	testModuleWith(t, fixedClockEvaluator, "time", []moduleTest{
+		{`time.format(0, time.RFC3339)`, "1970-01-01T00:00:00Z"},
+		{`time.format(time.now(), "Jan 2, 2006 at 3:04pm")`, "Feb 29, 2024 at 10:30pm"},
+		{`time.format(time.now(), time.DATE)`, "2024-02-29"},
+		{`time.format(time.now(), time.TIME)`, "22:30:15"},
+		{`time.parse("2024-03-01", time.DATE)`, 1709251200000},
+		{`time.parse("2024-03-01T01:00:00.5+01:00", time.RFC3339)`, 1709251200500},
+		{`time.parse(time.format(time.now(), time.RFC3339), time.RFC3339) == time.now()`, true},
+		{`time.parse("2024-02-30", time.DATE)`, errorMessage(`cannot parse time "2024-02-30": parsing time "2024-02-30": day out of range`)},
+		{`time.format("now", time.DATE)`, errorMessage("argument `time` to `time.format` must be INTEGER, got STRING")},
+	})
+}
+
+func TestTimeParts(t *testing.T) {
+	testModuleWith(t, fixedClockEvaluator, "time", []moduleTest{
+		{`time.parts(time.now()).year`, 2024},
+		{`time.parts(time.now()).month`, 2},
+		{`time.parts(time.now()).day`, 29},
+		{`time.parts(time.now()).hour`, 22},
+		{`time.parts(time.now()).millisecond`, 250},
+		{`time.parts(time.now()).weekday`, "Thursday"},
+	})
+}
@@ -7,15 +7,25 @@ type Lexer struct {
 	position     int
 	readPosition int
 	ch           byte
+
+	// line and column of ch
+	line   int
+	column int
 }
 
 func New(code string) *Lexer {
-	l := Lexer{code: code, readPosition: 0}
+	l := Lexer{code: code, readPosition: 0, line: 1}
 	l.readChar()
 	return &l
 }
 
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
 	if l.readPosition >= len(l.code) {
 		l.ch = 0
 	} else {
@@ -33,20 +43,81 @@ func isDigit(ch byte) bool {
 	return '0' <= ch && ch <= '9'
 }
 
+// readIdentifier reads a letter followed by letters and digits.
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isLetter(l.ch) {
+	for isLetter(l.ch) || isDigit(l.ch) {
 		l.readChar()
 	}
 	return l.code[position:l.position]
 }
 
-func (l *Lexer) readNumber() string {
+// readNumber reads an integer or a float. Floats have a fraction, an
+// exponent or both, as in 1.5, 1e9 and 2.5e-3. A dot that is not followed
+// by a digit is left alone, so that 1..2 and 1.foo still lex as before.
+func (l *Lexer) readNumber() (token.TokenType, string) {
 	position := l.position
+	tokenType := token.TokenType(token.INT)
+	l.readDigits()
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tokenType = token.FLOAT
+		l.readChar()
+		l.readDigits()
+	}
+	if (l.ch == 'e' || l.ch == 'E') && l.exponentFollows() {
+		tokenType = token.FLOAT
+		l.readChar()
+		if l.ch == '+' || l.ch == '-' {
+			l.readChar()
+		}
+		l.readDigits()
+	}
+	return tokenType, l.code[position:l.position]
+}
+
+func (l *Lexer) readDigits() {
 	for isDigit(l.ch) {
 		l.readChar()
 	}
-	return l.code[position:l.position]
+}
+
+// exponentFollows reports whether the 'e' at the current position starts
+// the exponent of a float.
+func (l *Lexer) exponentFollows() bool {
+	next := l.peekChar()
+	if next == '+' || next == '-' {
+		return l.readPosition+1 < len(l.code) && isDigit(l.code[l.readPosition+1])
+	}
+	return isDigit(next)
+}
+
+func (l *Lexer) readString() (string, bool) {
+	var out []byte
+	for {
+		l.readChar()
+		switch l.ch {
+		case '"':
+			return string(out), true
+		case 0:
+			return string(out), false
+		case '\\':
+			l.readChar()
+			switch l.ch {
+			case 'n':
+				out = append(out, '\n')
+			case 't':
+				out = append(out, '\t')
+			case 'r':
+				out = append(out, '\r')
+			case 0:
+				return string(out), false
+			default:
+				out = append(out, l.ch)
+			}
+		default:
+			out = append(out, l.ch)
+		}
+	}
 }
 
 func newToken(tokenType token.TokenType, ch byte) token.Token {
@@ -71,6 +142,7 @@ func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
 
 	l.skipWhitespace()
+	pos := token.Position{Line: l.line, Column: l.column}
 
 	switch l.ch {
 
@@ -78,12 +150,13 @@ func (l *Lexer) NextToken() token.Token {
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Pos = pos
 			return tok
 		}
 
 		if isDigit(l.ch) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
+			tok.Type, tok.Literal = l.readNumber()
+			tok.Pos = pos
 			return tok
 		}
 
@@ -93,6 +166,9 @@ func (l *Lexer) NextToken() token.Token {
 		if l.peekChar() == '=' {
 			l.readChar()
 			tok = token.Token{Type: token.EQ, Literal: "=="}
+		} else if l.peekChar() == '>' {
+			l.readChar()
+			tok = token.Token{Type: token.FAT_ARROW, Literal: "=>"}
 		} else {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
@@ -117,6 +193,39 @@ func (l *Lexer) NextToken() token.Token {
 		} else {
 			tok = newToken(token.GT, l.ch)
 		}
+	case '?':
+		switch l.peekChar() {
+		case '?':
+			l.readChar()
+			tok = token.Token{Type: token.NULLISH, Literal: "??"}
+		case '.':
+			l.readChar()
+			tok = token.Token{Type: token.OPT_DOT, Literal: "?."}
+		case '[':
+			l.readChar()
+			tok = token.Token{Type: token.OPT_LBRACKET, Literal: "?["}
+		default:
+			tok = newToken(token.QUESTION, l.ch)
+		}
+	case '.':
+		if l.peekChar() == '.' {
+			l.readChar()
+			if l.peekChar() == '.' {
+				l.readChar()
+				tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+			} else {
+				tok = token.Token{Type: token.DOTDOT, Literal: ".."}
+			}
+		} else {
+			tok = newToken(token.DOT, l.ch)
+		}
+	case '"':
+		literal, ok := l.readString()
+		if ok {
+			tok = token.Token{Type: token.STRING, Literal: literal}
+		} else {
+			tok = token.Token{Type: token.ILLEGAL, Literal: literal}
+		}
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
 	case '(':
@@ -129,6 +238,12 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.RBRACE, l.ch)
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
+	case ':':
+		tok = newToken(token.COLON, l.ch)
+	case '[':
+		tok = newToken(token.LBRACKET, l.ch)
+	case ']':
+		tok = newToken(token.RBRACKET, l.ch)
 	case '+':
 		tok = newToken(token.PLUS, l.ch)
 	case '-':
@@ -143,5 +258,6 @@ func (l *Lexer) NextToken() token.Token {
 	}
 
 	l.readChar()
+	tok.Pos = pos
 	return tok
 }
@@ -68,7 +68,7 @@ let result = add(five, ten);
 
 	l := lexer.New(code)
 	for _, e := range expected {
-		tok := l.NextToken()
+		tok := withoutPos(l.NextToken())
 		assert.Equal(t, e, tok)
 	}
 }
@@ -88,7 +88,7 @@ func TestBasicNextToken(t *testing.T) {
 
 	l := lexer.New(code)
 	for _, e := range expected {
-		tok := l.NextToken()
+		tok := withoutPos(l.NextToken())
 		assert.Equal(t, e, tok)
 	}
 }
@@ -106,6 +106,7 @@ func TestNextTokenOperators(t *testing.T) {
 		1-1
 		1+1
 		!
+		a??b
 	`
 	expected := []token.Token{
 		{Type: token.INT, Literal: "1"},
@@ -150,12 +151,16 @@ func TestNextTokenOperators(t *testing.T) {
 
 		{Type: token.BANG, Literal: "!"},
 
+		{Type: token.IDENT, Literal: "a"},
+		{Type: token.NULLISH, Literal: "??"},
+		{Type: token.IDENT, Literal: "b"},
+
 		{Type: token.EOF, Literal: "\x00"},
 	}
 
 	l := lexer.New(code)
 	for _, e := range expected {
-		tok := l.NextToken()
+		tok := withoutPos(l.NextToken())
 		assert.Equal(t, e, tok)
 	}
 }
@@ -169,6 +174,10 @@ func TestNextTokenKeywords(t *testing.T) {
 		if
 		else
 		return
+		null
+		import
+		export
+		as
 	`
 	expected := []token.Token{
 		{Type: token.FUNCTION, Literal: "fn"},
@@ -178,12 +187,172 @@ func TestNextTokenKeywords(t *testing.T) {
 		{Type: token.IF, Literal: "if"},
 		{Type: token.ELSE, Literal: "else"},
 		{Type: token.RETURN, Literal: "return"},
+		{Type: token.NULL, Literal: "null"},
+		{Type: token.IMPORT, Literal: "import"},
+		{Type: token.EXPORT, Literal: "export"},
+		{Type: token.AS, Literal: "as"},
 		{Type: token.EOF, Literal: "\x00"},
 	}
 
 	l := lexer.New(code)
 	for _, e := range expected {
-		tok := l.NextToken()
+		tok := withoutPos(l.NextToken())
 		assert.Equal(t, tok, e)
 	}
 }
+
+func TestNextTokenIdentifiersWithDigits(t *testing.T) {
+	code := "atan2 x1y 1x"
+	expected := []token.Token{
+		{Type: token.IDENT, Literal: "atan2"},
+		{Type: token.IDENT, Literal: "x1y"},
+		{Type: token.INT, Literal: "1"},
+		{Type: token.IDENT, Literal: "x"},
+		{Type: token.EOF, Literal: "\x00"},
+	}
+
+	l := lexer.New(code)
+	for _, e := range expected {
+		tok := withoutPos(l.NextToken())
+		assert.Equal(t, e, tok)
+	}
+}
+
+func TestNextTokenContainers(t *testing.T) {
+	code := `
+		"foo bar" "a\"b\n"
+		[1, 2]
+		{"a": 1}
+		a.b a?.b a?[0]
+		c ? 1 : 2
+		match (x) { [h, ..t] => h }
+		f(...args)
+		"unterminated
+	`
+	expected := []token.Token{
+		{Type: token.STRING, Literal: "foo bar"},
+		{Type: token.STRING, Literal: "a\"b\n"},
+
+		{Type: token.LBRACKET, Literal: "["},
+		{Type: token.INT, Literal: "1"},
+		{Type: token.COMMA, Literal: ","},
+		{Type: token.INT, Literal: "2"},
+		{Type: token.RBRACKET, Literal: "]"},
+
+		{Type: token.LBRACE, Literal: "{"},
+		{Type: token.STRING, Literal: "a"},
+		{Type: token.COLON, Literal: ":"},
+		{Type: token.INT, Literal: "1"},
+		{Type: token.RBRACE, Literal: "}"},
+
+		{Type: token.IDENT, Literal: "a"},
+		{Type: token.DOT, Literal: "."},
+		{Type: token.IDENT, Literal: "b"},
+		{Type: token.IDENT, Literal: "a"},
+		{Type: token.OPT_DOT, Literal: "?."},
+		{Type: token.IDENT, Literal: "b"},
+		{Type: token.IDENT, Literal: "a"},
+		{Type: token.OPT_LBRACKET, Literal: "?["},
+		{Type: token.INT, Literal: "0"},
+		{Type: token.RBRACKET, Literal: "]"},
+
+		{Type: token.IDENT, Literal: "c"},
+		{Type: token.QUESTION, Literal: "?"},
+		{Type: token.INT, Literal: "1"},
+		{Type: token.COLON, Literal: ":"},
+		{Type: token.INT, Literal: "2"},
+
+		{Type: token.MATCH, Literal: "match"},
+		{Type: token.LPAREN, Literal: "("},
+		{Type: token.IDENT, Literal: "x"},
+		{Type: token.RPAREN, Literal: ")"},
+		{Type: token.LBRACE, Literal: "{"},
+		{Type: token.LBRACKET, Literal: "["},
+		{Type: token.IDENT, Literal: "h"},
+		{Type: token.COMMA, Literal: ","},
+		{Type: token.DOTDOT, Literal: ".."},
+		{Type: token.IDENT, Literal: "t"},
+		{Type: token.RBRACKET, Literal: "]"},
+		{Type: token.FAT_ARROW, Literal: "=>"},
+		{Type: token.IDENT, Literal: "h"},
+		{Type: token.RBRACE, Literal: "}"},
+
+		{Type: token.IDENT, Literal: "f"},
+		{Type: token.LPAREN, Literal: "("},
+		{Type: token.ELLIPSIS, Literal: "..."},
+		{Type: token.IDENT, Literal: "args"},
+		{Type: token.RPAREN, Literal: ")"},
+
+		{Type: token.ILLEGAL, Literal: "unterminated\n\t"},
+		{Type: token.EOF, Literal: "\x00"},
+	}
+
+	l := lexer.New(code)
+	for _, e := range expected {
+		tok := withoutPos(l.NextToken())
+		assert.Equal(t, e, tok)
+	}
+}
+
+// withoutPos clears the token position so that tests focused on token types
+// and literals can compare whole tokens.
+func withoutPos(tok token.Token) token.Token {
+	tok.Pos = token.Position{}
+	return tok
+}
+
+func TestNextTokenPositions(t *testing.T) {
+	code := "let x = 5;\n  add(x,\n\t\"a b\");"
+	expected := []token.Token{
+		{Type: token.LET, Literal: "let", Pos: token.Position{Line: 1, Column: 1}},
+		{Type: token.IDENT, Literal: "x", Pos: token.Position{Line: 1, Column: 5}},
+		{Type: token.ASSIGN, Literal: "=", Pos: token.Position{Line: 1, Column: 7}},
+		{Type: token.INT, Literal: "5", Pos: token.Position{Line: 1, Column: 9}},
+		{Type: token.SEMICOLON, Literal: ";", Pos: token.Position{Line: 1, Column: 10}},
+		{Type: token.IDENT, Literal: "add", Pos: token.Position{Line: 2, Column: 3}},
+		{Type: token.LPAREN, Literal: "(", Pos: token.Position{Line: 2, Column: 6}},
+		{Type: token.IDENT, Literal: "x", Pos: token.Position{Line: 2, Column: 7}},
+		{Type: token.COMMA, Literal: ",", Pos: token.Position{Line: 2, Column: 8}},
+		{Type: token.STRING, Literal: "a b", Pos: token.Position{Line: 3, Column: 2}},
+		{Type: token.RPAREN, Literal: ")", Pos: token.Position{Line: 3, Column: 7}},
+		{Type: token.SEMICOLON, Literal: ";", Pos: token.Position{Line: 3, Column: 8}},
+		{Type: token.EOF, Literal: "\x00", Pos: token.Position{Line: 3, Column: 9}},
+	}
+
+	l := lexer.New(code)
+	for _, e := range expected {
+		tok := l.NextToken()
+		assert.Equal(t, e, tok)
+	}
+}
+
+func TestNextTokenNumbers(t *testing.T) {
+	code := "5 2.5 1e9 2.5E-3 7e+2 1..3 1.x 2e x.5 atan2 x1y"
+	expected := []token.Token{
+		{Type: token.INT, Literal: "5"},
+		{Type: token.FLOAT, Literal: "2.5"},
+		{Type: token.FLOAT, Literal: "1e9"},
+		{Type: token.FLOAT, Literal: "2.5E-3"},
+		{Type: token.FLOAT, Literal: "7e+2"},
+		{Type: token.INT, Literal: "1"},
+		{Type: token.DOTDOT, Literal: ".."},
+		{Type: token.INT, Literal: "3"},
+		{Type: token.INT, Literal: "1"},
+		{Type: token.DOT, Literal: "."},
+		{Type: token.IDENT, Literal: "x"},
+		{Type: token.INT, Literal: "2"},
+		{Type: token.IDENT, Literal: "e"},
+		{Type: token.IDENT, Literal: "x"},
+		{Type: token.DOT, Literal: "."},
+		{Type: token.INT, Literal: "5"},
+		{Type: token.IDENT, Literal: "atan2"},
+		{Type: token.IDENT, Literal: "x1y"},
+		{Type: token.EOF, Literal: "\x00"},
+	}
+
+	l := lexer.New(code)
+	for _, e := range expected {
+		tok := withoutPos(l.NextToken())
+		assert.Equal(t, e, tok)
+	}
+}
@@ -0,0 +1,66 @@
+// Package module finds and loads the source of the modules that scripts
+// import.
+//
+// Modules are named by slash-separated paths relative to the root of their
+// Loader, such as "lib/math.mk". Import paths are resolved against the
+// importing module with Resolve before they are loaded.
+package module
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Loader loads the source of modules by name. Hosts implement it to serve
+// modules from wherever they keep them.
+type Loader interface {
+	Load(name string) (string, error)
+}
+
+// Resolve returns the name of the module imported as importPath by the
+// module named from. Paths starting with a slash are relative to the root,
+// other paths to the directory of the importing module. The main program
+// has an empty name and imports relative to the root.
+func Resolve(from, importPath string) (string, error) {
+	if importPath == "" {
+		return "", fmt.Errorf("empty import path")
+	}
+	if strings.HasPrefix(importPath, "/") {
+		return path.Clean(importPath)[1:], nil
+	}
+	name := path.Join(path.Dir(from), importPath)
+	if name == ".." || strings.HasPrefix(name, "../") {
+		return "", fmt.Errorf("import path %q escapes the module root", importPath)
+	}
+	return name, nil
+}
+
+// FS returns a Loader that reads modules from fsys.
+func FS(fsys fs.FS) Loader {
+	return fsLoader{fsys: fsys}
+}
+
+type fsLoader struct {
+	fsys fs.FS
+}
+
+func (l fsLoader) Load(name string) (string, error) {
+	src, err := fs.ReadFile(l.fsys, name)
+	if err != nil {
+		return "", err
+	}
+	return string(src), nil
+}
+
+// Map is a Loader that serves modules from memory, keyed by name.
+type Map map[string]string
+
+func (m Map) Load(name string) (string, error) {
+	src, ok := m[name]
+	if !ok {
+		return "", &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return src, nil
+}
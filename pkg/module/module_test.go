@@ -0,0 +1,53 @@
+package module_test
+
+import (
+	"errors"
+	"github.com/muter3000/monkeparser/pkg/module"
+	"github.com/stretchr/testify/assert"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		from     string
+		path     string
+		expected string
+		err      string
+	}{
+		{"", "lib.mk", "lib.mk", ""},
+		{"", "./lib/math.mk", "lib/math.mk", ""},
+		{"lib/math.mk", "util.mk", "lib/util.mk", ""},
+		{"lib/math.mk", "../main.mk", "main.mk", ""},
+		{"lib/math.mk", "/other/a.mk", "other/a.mk", ""},
+		{"lib/math.mk", "/../a.mk", "a.mk", ""},
+		{"lib/math.mk", "../../a.mk", "", `import path "../../a.mk" escapes the module root`},
+		{"", "../a.mk", "", `import path "../a.mk" escapes the module root`},
+		{"", "", "", "empty import path"},
+	}
+	for _, tt := range tests {
+		name, err := module.Resolve(tt.from, tt.path)
+		if tt.err != "" {
+			assert.EqualError(t, err, tt.err)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, name)
+	}
+}
+
+func TestLoaders(t *testing.T) {
+	loaders := map[string]module.Loader{
+		"Map": module.Map{"lib/a.mk": "export let a = 1;"},
+		"FS":  module.FS(fstest.MapFS{"lib/a.mk": {Data: []byte("export let a = 1;")}}),
+	}
+	for name, loader := range loaders {
+		src, err := loader.Load("lib/a.mk")
+		assert.NoError(t, err, name)
+		assert.Equal(t, "export let a = 1;", src, name)
+
+		_, err = loader.Load("lib/b.mk")
+		assert.True(t, errors.Is(err, fs.ErrNotExist), name)
+	}
+}
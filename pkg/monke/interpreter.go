@@ -0,0 +1,179 @@
+// Package monke embeds the Monke interpreter in Go programs.
+package monke
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/muter3000/monkeparser/pkg/evaluator"
+	"github.com/muter3000/monkeparser/pkg/lexer"
+	"github.com/muter3000/monkeparser/pkg/module"
+	"github.com/muter3000/monkeparser/pkg/object"
+	"github.com/muter3000/monkeparser/pkg/parser"
+	"github.com/muter3000/monkeparser/pkg/vfs"
+	"io"
+	"io/fs"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Interpreter runs Monke source against its own global environment. The
+// globals persist from one call to the next. Interpreters share no mutable
+// state, so any number of them can run concurrently; calls on a single
+// Interpreter are serialized.
+type Interpreter struct {
+	mu  sync.Mutex
+	env *object.Environment
+
+	builtins  map[string]*object.Builtin
+	stdout    io.Writer
+	stderr    io.Writer
+	stdin     io.Reader
+	maxDepth  int
+	maxSteps  int64
+	maxMemory int64
+	fsys      fs.FS
+	writeFS   vfs.WriteFS
+	loader    module.Loader
+	clock     func() time.Time
+	rand      *rand.Rand // Shared by all calls, so that the sequence continues
+
+	modules map[string]*object.Module // Evaluated once per interpreter
+	usage   evaluator.Usage
+}
+
+func New(opts ...Option) *Interpreter {
+	i := &Interpreter{
+		env:      object.NewEnvironment(),
+		builtins: evaluator.DefaultBuiltins(),
+		stdout:   os.Stdout,
+		stderr:   os.Stderr,
+		maxDepth: evaluator.DefaultMaxDepth,
+		modules:  make(map[string]*object.Module),
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	if i.fsys == nil {
+		i.fsys = i.writeFS
+	}
+	if i.stdin != nil {
+		// Buffered once, so that lines read ahead by one call are left
+		// for the next.
+		i.stdin = bufio.NewReader(i.stdin)
+	}
+	if i.rand == nil {
+		i.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return i
+}
+
+// ParseError is returned for source that does not parse.
+type ParseError struct {
+	Messages []string
+}
+
+func (e *ParseError) Error() string {
+	return "parse error: " + strings.Join(e.Messages, "; ")
+}
+
+// RuntimeError is returned when evaluation ends with a Monke error. For
+// cancelled evaluations it unwraps to the error of the context.
+type RuntimeError struct {
+	Object *object.Error
+
+	cause error
+}
+
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Object.Kind, e.Object.Message)
+}
+
+func (e *RuntimeError) Unwrap() error { return e.cause }
+
+// Eval parses and evaluates src and returns the value of its last
+// statement, or null if it has none.
+func (i *Interpreter) Eval(ctx context.Context, src string) (object.Object, error) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &ParseError{Messages: p.Errors()}
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	e := i.newEvaluator()
+	return i.result(ctx, e, e.EvalContext(ctx, program, i.env))
+}
+
+// Call calls the global function named fnName with args.
+func (i *Interpreter) Call(ctx context.Context, fnName string, args ...object.Object) (object.Object, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	e := i.newEvaluator()
+	fn, ok := i.env.Get(fnName)
+	if !ok {
+		fn, ok = i.builtins[fnName]
+	}
+	if !ok {
+		return nil, &RuntimeError{Object: object.NewError(object.NameError, "identifier not found: %s", fnName)}
+	}
+	return i.result(ctx, e, e.ApplyContext(ctx, fn, args...))
+}
+
+// Set binds a global variable.
+func (i *Interpreter) Set(name string, value object.Object) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.env.Set(name, value)
+}
+
+// Get looks up a global variable.
+func (i *Interpreter) Get(name string) (object.Object, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.env.Get(name)
+}
+
+// Usage reports the resources used by the last call to Eval or Call.
+func (i *Interpreter) Usage() evaluator.Usage {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.usage
+}
+
+func (i *Interpreter) newEvaluator() *evaluator.Evaluator {
+	e := evaluator.New()
+	e.Builtins = i.builtins
+	e.Stdout = i.stdout
+	e.Stderr = i.stderr
+	e.Stdin = i.stdin
+	e.MaxDepth = i.maxDepth
+	e.MaxSteps = i.maxSteps
+	e.MaxMemory = i.maxMemory
+	e.FS = i.fsys
+	e.WriteFS = i.writeFS
+	e.Loader = i.loader
+	e.Clock = i.clock
+	e.Rand = i.rand
+	e.Modules = i.modules
+	return e
+}
+
+func (i *Interpreter) result(ctx context.Context, e *evaluator.Evaluator, obj object.Object) (object.Object, error) {
+	i.usage = e.Usage()
+	if errObj, ok := obj.(*object.Error); ok {
+		err := &RuntimeError{Object: errObj}
+		if errObj.Kind == object.CancelledError {
+			err.cause = ctx.Err()
+		}
+		return nil, err
+	}
+	if obj == nil {
+		return evaluator.NULL, nil
+	}
+	return obj, nil
+}
@@ -0,0 +1,338 @@
+package monke_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/muter3000/monkeparser/pkg/module"
+	"github.com/muter3000/monkeparser/pkg/monke"
+	"github.com/muter3000/monkeparser/pkg/object"
+	"github.com/muter3000/monkeparser/pkg/vfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/fs"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInterpreterEval(t *testing.T) {
+	interp := monke.New()
+	ctx := context.Background()
+
+	result, err := interp.Eval(ctx, "let add = fn(a, b) { a + b }")
+	require.NoError(t, err)
+	assert.Equal(t, "null", result.Inspect())
+
+	result, err = interp.Eval(ctx, "add(2, 3)")
+	require.NoError(t, err)
+	assert.Equal(t, &object.Integer{Value: 5}, result)
+}
+
+func TestInterpreterErrors(t *testing.T) {
+	interp := monke.New()
+	ctx := context.Background()
+
+	_, err := interp.Eval(ctx, "let = 1")
+	var parseErr *monke.ParseError
+	require.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, "expected next token to be 'IDENT', got = instead", parseErr.Messages[0])
+
+	_, err = interp.Eval(ctx, "1 + missing")
+	var runtimeErr *monke.RuntimeError
+	require.ErrorAs(t, err, &runtimeErr)
+	assert.Equal(t, "NameError: identifier not found: missing", err.Error())
+	assert.Equal(t, object.NameError, runtimeErr.Object.Kind)
+}
+
+func TestInterpreterCall(t *testing.T) {
+	interp := monke.New()
+	ctx := context.Background()
+	_, err := interp.Eval(ctx, "let greet = fn(name, greeting = \"Hello\") { greeting + \", \" + name }")
+	require.NoError(t, err)
+
+	result, err := interp.Call(ctx, "greet", &object.String{Value: "Monke"})
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, Monke", result.Inspect())
+
+	result, err = interp.Call(ctx, "len", &object.String{Value: "Monke"})
+	require.NoError(t, err)
+	assert.Equal(t, "5", result.Inspect())
+
+	_, err = interp.Call(ctx, "greet")
+	assert.EqualError(t, err, "ArgumentError: wrong number of arguments. got=0, want=1..2")
+
+	_, err = interp.Call(ctx, "missing")
+	assert.EqualError(t, err, "NameError: identifier not found: missing")
+}
+
+func TestInterpreterGlobals(t *testing.T) {
+	interp := monke.New()
+	ctx := context.Background()
+
+	interp.Set("limit", &object.Integer{Value: 10})
+	result, err := interp.Eval(ctx, "let doubled = limit * 2; doubled")
+	require.NoError(t, err)
+	assert.Equal(t, "20", result.Inspect())
+
+	doubled, ok := interp.Get("doubled")
+	require.True(t, ok)
+	assert.Equal(t, "20", doubled.Inspect())
+
+	_, ok = interp.Get("missing")
+	assert.False(t, ok)
+}
+
+func TestInterpreterBuiltinOptions(t *testing.T) {
+	answer := &object.Builtin{
+		Name: "answer",
+		Fn: func(args ...object.Object) object.Object {
+			return &object.Integer{Value: 42}
+		},
+	}
+	interp := monke.New(monke.WithBuiltins(answer), monke.WithoutBuiltins("len"))
+	ctx := context.Background()
+
+	result, err := interp.Eval(ctx, "answer()")
+	require.NoError(t, err)
+	assert.Equal(t, "42", result.Inspect())
+
+	_, err = interp.Eval(ctx, "len([1])")
+	assert.EqualError(t, err, "NameError: identifier not found: len")
+
+	result, err = monke.New().Eval(ctx, "len([1])")
+	require.NoError(t, err)
+	assert.Equal(t, "1", result.Inspect())
+}
+
+func TestInterpreterLimits(t *testing.T) {
+	ctx := context.Background()
+	loop := "let loop = fn(n) { loop(n + 1) }; loop(0)"
+
+	_, err := monke.New(monke.WithMaxSteps(100)).Eval(ctx, loop)
+	assert.EqualError(t, err, "LimitError: step limit of 100 exceeded")
+
+	_, err = monke.New(monke.WithMaxMemory(1000)).Eval(ctx, loop)
+	assert.EqualError(t, err, "LimitError: memory limit of 1000 bytes exceeded")
+
+	_, err = monke.New(monke.WithMaxDepth(10)).Eval(ctx, "let f = fn(n) { f(n + 1) + 1 }; f(0)")
+	assert.EqualError(t, err, "RecursionError: maximum recursion depth exceeded")
+
+	interp := monke.New()
+	_, err = interp.Eval(ctx, "1 + 2")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), interp.Usage().Steps)
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = interp.Eval(ctx, loop)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestConcurrentInterpreters(t *testing.T) {
+	ctx := context.Background()
+	shared := monke.New()
+	_, err := shared.Eval(ctx, "let counter = 0")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for n := 0; n < 8; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			interp := monke.New()
+			interp.Set("n", &object.Integer{Value: int64(n)})
+			result, err := interp.Eval(ctx, "let sum = fn(k) { if (k == 0) { 0 } else { k + sum(k - 1) } }; sum(100) + n")
+			assert.NoError(t, err)
+			assert.Equal(t, fmt.Sprint(5050+n), result.Inspect())
+
+			for k := 0; k < 10; k++ {
+				_, err := shared.Eval(ctx, "let counter = counter + 1")
+				assert.NoError(t, err)
+			}
+		}(n)
+	}
+	wg.Wait()
+
+	counter, _ := shared.Get("counter")
+	assert.Equal(t, "80", counter.Inspect())
+}
+
+func TestInterpreterGoValues(t *testing.T) {
+	type order struct {
+		ID    int      `monke:"id"`
+		Items []string `monke:"items"`
+	}
+	interp := monke.New()
+	ctx := context.Background()
+
+	orderObj, err := object.FromGo(order{ID: 7, Items: []string{"apple", "pear"}})
+	require.NoError(t, err)
+	interp.Set("order", orderObj)
+	lookup, err := object.FromGo(func(id int) (string, error) {
+		if id != 7 {
+			return "", fmt.Errorf("no order %d", id)
+		}
+		return "shipped", nil
+	})
+	require.NoError(t, err)
+	interp.Set("status", lookup)
+
+	result, err := interp.Eval(ctx, `{"id": order.id, "status": status(order.id), "count": len(order.items)}`)
+	require.NoError(t, err)
+	var summary struct {
+		ID     int    `monke:"id"`
+		Status string `monke:"status"`
+		Count  int    `monke:"count"`
+	}
+	require.NoError(t, object.ToGo(result, &summary))
+	assert.Equal(t, 7, summary.ID)
+	assert.Equal(t, "shipped", summary.Status)
+	assert.Equal(t, 2, summary.Count)
+
+	_, err = interp.Eval(ctx, "status(1)")
+	assert.EqualError(t, err, "RuntimeError: no order 1")
+}
+
+func TestInterpreterSharedPrelude(t *testing.T) {
+	ctx := context.Background()
+	prelude := object.NewEnvironment()
+	lib := monke.New()
+	_, err := lib.Eval(ctx, "let square = fn(x) { x * x }")
+	require.NoError(t, err)
+	square, _ := lib.Get("square")
+	prelude.Set("square", square)
+
+	var wg sync.WaitGroup
+	for n := 0; n < 16; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			interp := monke.New(monke.WithPrelude(prelude))
+			interp.Set("n", &object.Integer{Value: int64(n)})
+			result, err := interp.Eval(ctx, "let square = square(n); square")
+			assert.NoError(t, err)
+			assert.Equal(t, fmt.Sprint(n*n), result.Inspect())
+		}(n)
+	}
+	wg.Wait()
+	assert.True(t, prelude.Frozen())
+}
+
+func TestInterpreterModules(t *testing.T) {
+	ctx := context.Background()
+	interp := monke.New(monke.WithLoader(module.Map{
+		"lib.mk": "let count = 0; export let answer = 42;",
+	}))
+
+	_, err := interp.Eval(ctx, `import "lib.mk" as lib`)
+	require.NoError(t, err)
+	_, err = interp.Eval(ctx, `import "lib.mk" as again`)
+	require.NoError(t, err)
+	lib, _ := interp.Get("lib")
+	again, _ := interp.Get("again")
+	assert.Same(t, lib, again)
+	result, err := interp.Eval(ctx, "lib.answer")
+	require.NoError(t, err)
+	assert.Equal(t, &object.Integer{Value: 42}, result)
+
+	_, err = monke.New().Eval(ctx, `import "lib.mk" as lib`)
+	assert.EqualError(t, err, "ImportError: cannot import lib.mk: no module loader")
+}
+
+func TestInterpreterFS(t *testing.T) {
+	ctx := context.Background()
+	fsys := vfs.NewMemory(map[string]string{"lib/a.mk": `import "b.mk" as b; export let a = b.b + 1;`, "lib/b.mk": "export let b = 1;"})
+
+	result, err := monke.New(monke.WithFS(fsys)).Eval(ctx, `import "lib/a.mk" as a; a.a`)
+	require.NoError(t, err)
+	assert.Equal(t, &object.Integer{Value: 2}, result)
+
+	interp := monke.New(monke.WithFS(fsys), monke.WithLoader(module.Map{"lib/a.mk": "export let a = 10;"}))
+	result, err = interp.Eval(ctx, `import "lib/a.mk" as a; a.a`)
+	require.NoError(t, err)
+	assert.Equal(t, &object.Integer{Value: 10}, result)
+}
+
+func TestInterpreterDeterminism(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 2, 29, 13, 45, 0, 0, time.UTC)
+	script := `
+		import "time" as time;
+		import "random" as random;
+		[time.format(time.now() + time.days(1), time.DATETIME), random.int(1, 1000000), random.shuffle([1, 2, 3, 4, 5])]`
+
+	run := func(seed int64) string {
+		interp := monke.New(monke.WithClock(func() time.Time { return now }), monke.WithRandSeed(seed))
+		first, err := interp.Eval(ctx, script)
+		require.NoError(t, err)
+		// The sequence continues across calls instead of starting over
+		second, err := interp.Eval(ctx, script)
+		require.NoError(t, err)
+		assert.NotEqual(t, first.Inspect(), second.Inspect())
+		return first.Inspect() + second.Inspect()
+	}
+	assert.Equal(t, run(42), run(42))
+	assert.NotEqual(t, run(42), run(43))
+	assert.Contains(t, run(42), "2024-03-01 13:45:00")
+}
+
+func TestInterpreterIO(t *testing.T) {
+	ctx := context.Background()
+	var out strings.Builder
+	fsys := vfs.NewMemory(map[string]string{"in.txt": "data"})
+	interp := monke.New(
+		monke.WithStdout(&out),
+		monke.WithStdin(strings.NewReader("first\nsecond\n")),
+		monke.WithWriteFS(fsys),
+	)
+
+	_, err := interp.Eval(ctx, `println("line:", read_line()); print(read_file("in.txt"))`)
+	require.NoError(t, err)
+	// Input buffered by the first call is still there for the next
+	result, err := interp.Eval(ctx, `write_file("out.txt", read_line()); read_line()`)
+	require.NoError(t, err)
+	assert.Equal(t, object.NULL, result)
+	assert.Equal(t, "line: first\ndata", out.String())
+	data, err := fs.ReadFile(fsys, "out.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(data))
+}
+
+func TestInterpreterReadAndWriteFS(t *testing.T) {
+	ctx := context.Background()
+	reads := vfs.NewMemory(map[string]string{"in.txt": "read"})
+	for _, opts := range [][]monke.Option{
+		{monke.WithFS(reads), monke.WithWriteFS(vfs.NewMemory(nil))},
+		{monke.WithWriteFS(vfs.NewMemory(nil)), monke.WithFS(reads)},
+	} {
+		result, err := monke.New(opts...).Eval(ctx, `write_file("in.txt", "written"); read_file("in.txt")`)
+		require.NoError(t, err)
+		assert.Equal(t, "read", result.Inspect())
+	}
+
+	writes := vfs.NewMemory(map[string]string{"in.txt": "old"})
+	result, err := monke.New(monke.WithWriteFS(writes)).Eval(ctx, `write_file("in.txt", "new"); read_file("in.txt")`)
+	require.NoError(t, err)
+	assert.Equal(t, "new", result.Inspect())
+}
+
+func TestInterpreterSandbox(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		interp   *monke.Interpreter
+		input    string
+		expected string
+	}{
+		{monke.New(), `read_file("go.mod")`, "PermissionError: read_file is not permitted: no filesystem"},
+		{monke.New(), `write_file("x", "")`, "PermissionError: write_file is not permitted: no writable filesystem"},
+		{monke.New(monke.WithFS(vfs.NewMemory(nil))), `write_file("x", "")`, "PermissionError: write_file is not permitted: no writable filesystem"},
+		{monke.New(), `read_line()`, "PermissionError: read_line is not permitted: no input"},
+		{monke.New(monke.WithStdin(nil)), `read_line()`, "PermissionError: read_line is not permitted: no input"},
+		{monke.New(monke.WithoutBuiltins("print")), `print(1)`, "NameError: identifier not found: print"},
+	}
+	for _, tt := range tests {
+		_, err := tt.interp.Eval(ctx, tt.input)
+		assert.EqualError(t, err, tt.expected, tt.input)
+	}
+}
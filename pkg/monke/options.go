@@ -0,0 +1,112 @@
+package monke
+
+import (
+	"github.com/muter3000/monkeparser/pkg/module"
+	"github.com/muter3000/monkeparser/pkg/object"
+	"github.com/muter3000/monkeparser/pkg/vfs"
+	"io"
+	"io/fs"
+	"math/rand"
+	"time"
+)
+
+// Option configures an Interpreter.
+type Option func(*Interpreter)
+
+// WithBuiltins makes the given builtins available under their names,
+// replacing default builtins of the same name.
+func WithBuiltins(builtins ...*object.Builtin) Option {
+	return func(i *Interpreter) {
+		for _, builtin := range builtins {
+			i.builtins[builtin.Name] = builtin
+		}
+	}
+}
+
+// WithoutBuiltins removes the named default builtins.
+func WithoutBuiltins(names ...string) Option {
+	return func(i *Interpreter) {
+		for _, name := range names {
+			delete(i.builtins, name)
+		}
+	}
+}
+
+// WithStdout sets where scripts write their output. It defaults to
+// os.Stdout.
+func WithStdout(w io.Writer) Option {
+	return func(i *Interpreter) { i.stdout = w }
+}
+
+// WithStderr sets where scripts write their error output. It defaults to
+// os.Stderr.
+func WithStderr(w io.Writer) Option {
+	return func(i *Interpreter) { i.stderr = w }
+}
+
+// WithStdin sets where scripts read input from with read_line. Without it,
+// or with nil, read_line fails with a PermissionError.
+func WithStdin(r io.Reader) Option {
+	return func(i *Interpreter) { i.stdin = r }
+}
+
+// WithMaxDepth limits the depth of nested function calls.
+func WithMaxDepth(depth int) Option {
+	return func(i *Interpreter) { i.maxDepth = depth }
+}
+
+// WithMaxSteps limits the number of nodes one call to Eval or Call may
+// evaluate.
+func WithMaxSteps(steps int64) Option {
+	return func(i *Interpreter) { i.maxSteps = steps }
+}
+
+// WithMaxMemory limits the approximate number of bytes one call to Eval or
+// Call may allocate.
+func WithMaxMemory(bytes int64) Option {
+	return func(i *Interpreter) { i.maxMemory = bytes }
+}
+
+// WithFS gives scripts read access to fsys, which the vfs package provides
+// implementations of. Modules are loaded from it too unless WithLoader is
+// given. Without a filesystem, scripts cannot touch any files: read_file
+// and write_file fail with a PermissionError.
+func WithFS(fsys fs.FS) Option {
+	return func(i *Interpreter) { i.fsys = fsys }
+}
+
+// WithWriteFS lets scripts write files in fsys with write_file. Scripts
+// also read from fsys unless WithFS is given, in either order, so that a
+// host can grant reads and writes in different filesystems.
+func WithWriteFS(fsys vfs.WriteFS) Option {
+	return func(i *Interpreter) { i.writeFS = fsys }
+}
+
+// WithLoader sets where imported modules are loaded from, overriding
+// WithFS. Without a loader or filesystem, scripts cannot import modules.
+func WithLoader(loader module.Loader) Option {
+	return func(i *Interpreter) { i.loader = loader }
+}
+
+// WithClock sets the clock that the time module reads, so that hosts can
+// fix or control the time scripts see. It defaults to time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(i *Interpreter) { i.clock = now }
+}
+
+// WithRandSeed seeds the random module. Interpreters with the same seed
+// that run the same scripts get the same random values. Without a seed,
+// the interpreter is seeded from the current time.
+func WithRandSeed(seed int64) Option {
+	return func(i *Interpreter) { i.rand = rand.New(rand.NewSource(seed)) }
+}
+
+// WithPrelude makes the globals of env visible to scripts, below the
+// interpreter's own globals. env is frozen so that it can be shared by any
+// number of interpreters running concurrently.
+func WithPrelude(env *object.Environment) Option {
+	return func(i *Interpreter) {
+		env.Freeze()
+		i.env = object.NewEnclosedEnvironment(env)
+	}
+}
@@ -0,0 +1,459 @@
+package object
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// maxConvertDepth bounds how deeply nested values FromGo and ToGo follow, so
+// that cyclic Go values fail instead of recursing forever.
+const maxConvertDepth = 100
+
+var (
+	objectType = reflect.TypeOf((*Object)(nil)).Elem()
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// FromGo converts a Go value to an object:
+//
+//   - nil and nil pointers, maps and slices become null
+//   - bools, integers, floats and strings become booleans, integers, floats
+//     and strings
+//   - slices and arrays become arrays, and maps become hashes
+//   - structs become hashes of their exported fields
+//   - funcs become builtins, see below
+//   - objects are returned unchanged
+//
+// Struct fields are named by a `monke:"name"` tag, or by the field name if
+// the tag is missing. Fields tagged `monke:"-"` are left out.
+//
+// A func becomes a builtin that converts its arguments with ToGo and its
+// result with FromGo. It may return nothing, a value, an error, or a value
+// and an error; a non-nil error is raised as a RuntimeError.
+func FromGo(value any) (Object, error) {
+	if value == nil {
+		return NULL, nil
+	}
+	return fromGo(reflect.ValueOf(value), 0)
+}
+
+func fromGo(v reflect.Value, depth int) (Object, error) {
+	if depth > maxConvertDepth {
+		return nil, fmt.Errorf("cannot convert %s: value nested too deeply", v.Type())
+	}
+	if v.Type().Implements(objectType) && v.Kind() != reflect.Interface {
+		if v.IsNil() {
+			return NULL, nil
+		}
+		return v.Interface().(Object), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return TRUE, nil
+		}
+		return FALSE, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Integer{Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("cannot convert %s %d: out of range for INTEGER", v.Type(), v.Uint())
+		}
+		return &Integer{Value: int64(v.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Float{Value: v.Float()}, nil
+	case reflect.String:
+		return &String{Value: v.String()}, nil
+
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		return fromGo(v.Elem(), depth+1)
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return NULL, nil
+		}
+		elements := make([]Object, v.Len())
+		for i := range elements {
+			el, err := fromGo(v.Index(i), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = el
+		}
+		return &Array{Elements: elements}, nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		pairs := make(map[HashKey]HashPair, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := fromGo(iter.Key(), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			hashable, ok := key.(Hashable)
+			if !ok {
+				return nil, fmt.Errorf("cannot convert %s: unusable as hash key: %s", v.Type(), key.Type())
+			}
+			value, err := fromGo(iter.Value(), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			pairs[hashable.HashKey()] = HashPair{Key: key, Value: value}
+		}
+		return &Hash{Pairs: pairs}, nil
+
+	case reflect.Struct:
+		pairs := make(map[HashKey]HashPair)
+		for _, field := range structFields(v.Type()) {
+			value, err := fromGo(v.FieldByIndex(field.index), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			key := &String{Value: field.name}
+			pairs[key.HashKey()] = HashPair{Key: key, Value: value}
+		}
+		return &Hash{Pairs: pairs}, nil
+
+	case reflect.Func:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		return wrapFunc(v)
+
+	default:
+		return nil, fmt.Errorf("cannot convert %s to an object", v.Type())
+	}
+}
+
+// ToGo stores obj in the value that target points to, converting it to the
+// type of that value the opposite way to FromGo. Null sets the zero value.
+// Hashes fill structs field by field; keys without a matching field are
+// ignored. Floats accept integers too. Empty interfaces receive int64,
+// float64, bool, string, nil, []any or
+// map[string]any, while other interfaces such as Object receive the object
+// itself.
+func ToGo(obj Object, target any) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("ToGo target must be a non-nil pointer, got %T", target)
+	}
+	return toGo(obj, v.Elem(), "", 0)
+}
+
+func toGo(obj Object, v reflect.Value, path string, depth int) error {
+	if obj == nil {
+		return fmt.Errorf("%scannot convert a nil Object", pathPrefix(path))
+	}
+	if depth > maxConvertDepth {
+		return fmt.Errorf("%scannot convert %s: value nested too deeply", pathPrefix(path), obj.Type())
+	}
+	if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+		native, err := toNative(obj, path, depth)
+		if err != nil {
+			return err
+		}
+		if native == nil {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			v.Set(reflect.ValueOf(native))
+		}
+		return nil
+	}
+	if reflect.TypeOf(obj).AssignableTo(v.Type()) {
+		v.Set(reflect.ValueOf(obj))
+		return nil
+	}
+	if obj == NULL {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	mismatch := func() error {
+		return fmt.Errorf("%scannot convert %s to %s", pathPrefix(path), obj.Type(), v.Type())
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		b, ok := obj.(*Boolean)
+		if !ok {
+			return mismatch()
+		}
+		v.SetBool(b.Value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := obj.(*Integer)
+		if !ok {
+			return mismatch()
+		}
+		if v.OverflowInt(i.Value) {
+			return fmt.Errorf("%sinteger %d out of range for %s", pathPrefix(path), i.Value, v.Type())
+		}
+		v.SetInt(i.Value)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, ok := obj.(*Integer)
+		if !ok {
+			return mismatch()
+		}
+		if i.Value < 0 || v.OverflowUint(uint64(i.Value)) {
+			return fmt.Errorf("%sinteger %d out of range for %s", pathPrefix(path), i.Value, v.Type())
+		}
+		v.SetUint(uint64(i.Value))
+	case reflect.Float32, reflect.Float64:
+		switch n := obj.(type) {
+		case *Integer:
+			v.SetFloat(float64(n.Value))
+		case *Float:
+			if v.OverflowFloat(n.Value) {
+				return fmt.Errorf("%sfloat %g out of range for %s", pathPrefix(path), n.Value, v.Type())
+			}
+			v.SetFloat(n.Value)
+		default:
+			return mismatch()
+		}
+	case reflect.String:
+		s, ok := obj.(*String)
+		if !ok {
+			return mismatch()
+		}
+		v.SetString(s.Value)
+
+	case reflect.Pointer:
+		elem := reflect.New(v.Type().Elem())
+		if err := toGo(obj, elem.Elem(), path, depth+1); err != nil {
+			return err
+		}
+		v.Set(elem)
+
+	case reflect.Slice:
+		array, ok := obj.(*Array)
+		if !ok {
+			return mismatch()
+		}
+		slice := reflect.MakeSlice(v.Type(), len(array.Elements), len(array.Elements))
+		for i, el := range array.Elements {
+			if err := toGo(el, slice.Index(i), fmt.Sprintf("%s[%d]", path, i), depth+1); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Array:
+		array, ok := obj.(*Array)
+		if !ok {
+			return mismatch()
+		}
+		if len(array.Elements) != v.Len() {
+			return fmt.Errorf("%scannot convert ARRAY of %d elements to %s", pathPrefix(path), len(array.Elements), v.Type())
+		}
+		for i, el := range array.Elements {
+			if err := toGo(el, v.Index(i), fmt.Sprintf("%s[%d]", path, i), depth+1); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		hash, ok := obj.(*Hash)
+		if !ok {
+			return mismatch()
+		}
+		m := reflect.MakeMapWithSize(v.Type(), len(hash.Pairs))
+		for _, pair := range hash.Pairs {
+			key := reflect.New(v.Type().Key()).Elem()
+			if err := toGo(pair.Key, key, path, depth+1); err != nil {
+				return err
+			}
+			value := reflect.New(v.Type().Elem()).Elem()
+			if err := toGo(pair.Value, value, memberPath(path, pair.Key), depth+1); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, value)
+		}
+		v.Set(m)
+	case reflect.Struct:
+		hash, ok := obj.(*Hash)
+		if !ok {
+			return mismatch()
+		}
+		for _, field := range structFields(v.Type()) {
+			value, ok := hash.Get(field.name)
+			if !ok {
+				continue
+			}
+			fieldPath := memberPath(path, &String{Value: field.name})
+			if err := toGo(value, v.FieldByIndex(field.index), fieldPath, depth+1); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return mismatch()
+	}
+	return nil
+}
+
+// toNative converts obj to the plain Go value stored in an empty interface.
+// Functions and other objects without a Go counterpart are kept as they are.
+func toNative(obj Object, path string, depth int) (any, error) {
+	if obj == nil {
+		return nil, fmt.Errorf("%scannot convert a nil Object", pathPrefix(path))
+	}
+	if depth > maxConvertDepth {
+		return nil, fmt.Errorf("%scannot convert %s: value nested too deeply", pathPrefix(path), obj.Type())
+	}
+	switch obj := obj.(type) {
+	case *Integer:
+		return obj.Value, nil
+	case *Float:
+		return obj.Value, nil
+	case *Boolean:
+		return obj.Value, nil
+	case *String:
+		return obj.Value, nil
+	case *Null:
+		return nil, nil
+	case *Array:
+		elements := make([]any, len(obj.Elements))
+		for i, el := range obj.Elements {
+			native, err := toNative(el, fmt.Sprintf("%s[%d]", path, i), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = native
+		}
+		return elements, nil
+	case *Hash:
+		m := make(map[string]any, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			native, err := toNative(pair.Value, memberPath(path, pair.Key), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			m[pair.Key.Inspect()] = native
+		}
+		return m, nil
+	default:
+		return obj, nil
+	}
+}
+
+type structField struct {
+	name  string
+	index []int
+}
+
+// structFields lists the exported fields of t with their Monke names.
+func structFields(t reflect.Type) []structField {
+	var fields []structField
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("monke"); ok {
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		fields = append(fields, structField{name: name, index: field.Index})
+	}
+	return fields
+}
+
+func pathPrefix(path string) string {
+	if path == "" {
+		return ""
+	}
+	return strings.TrimPrefix(path, ".") + ": "
+}
+
+func memberPath(path string, key Object) string {
+	if s, ok := key.(*String); ok {
+		return path + "." + s.Value
+	}
+	return fmt.Sprintf("%s[%s]", path, key.Inspect())
+}
+
+// wrapFunc turns a Go func into a builtin that converts its arguments and
+// results. The builtin is named after the func, and a panic in the func is
+// raised as a RuntimeError instead of crashing the host.
+func wrapFunc(fn reflect.Value) (*Builtin, error) {
+	t := fn.Type()
+	switch {
+	case t.NumOut() > 2,
+		t.NumOut() == 2 && t.Out(1) != errorType:
+		return nil, fmt.Errorf("cannot convert %s: a func must return at most a value and an error", t)
+	}
+
+	builtin := &Builtin{Name: funcName(fn)}
+	builtin.Fn = func(args ...Object) (result Object) {
+		required := t.NumIn()
+		if t.IsVariadic() {
+			required--
+		}
+		if len(args) < required || (!t.IsVariadic() && len(args) > required) {
+			want := fmt.Sprintf("%d", required)
+			if t.IsVariadic() {
+				want += "+"
+			}
+			return NewError(ArgumentError, "wrong number of arguments. got=%d, want=%s", len(args), want)
+		}
+
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			var paramType reflect.Type
+			if t.IsVariadic() && i >= required {
+				paramType = t.In(required).Elem()
+			} else {
+				paramType = t.In(i)
+			}
+			param := reflect.New(paramType).Elem()
+			if err := toGo(arg, param, "", 0); err != nil {
+				return NewError(TypeError, "argument %d: %s", i+1, err)
+			}
+			in[i] = param
+		}
+
+		defer func() {
+			if r := recover(); r != nil {
+				result = NewError(RuntimeError, "%s panicked: %v", builtin.Name, r)
+			}
+		}()
+		out := fn.Call(in)
+		if len(out) > 0 && out[len(out)-1].Type() == errorType {
+			if err, _ := out[len(out)-1].Interface().(error); err != nil {
+				return NewError(RuntimeError, "%s", err)
+			}
+			out = out[:len(out)-1]
+		}
+		if len(out) == 0 {
+			return NULL
+		}
+		converted, err := fromGo(out[0], 0)
+		if err != nil {
+			return NewError(TypeError, "result: %s", err)
+		}
+		return converted
+	}
+	return builtin, nil
+}
+
+// funcName returns the name of a Go func without its package path, such as
+// strings.ToUpper.
+func funcName(fn reflect.Value) string {
+	f := runtime.FuncForPC(fn.Pointer())
+	if f == nil {
+		return "func"
+	}
+	name := f.Name()
+	return name[strings.LastIndex(name, "/")+1:]
+}
@@ -0,0 +1,214 @@
+package object_test
+
+import (
+	"errors"
+	"github.com/muter3000/monkeparser/pkg/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"strings"
+	"testing"
+)
+
+type address struct {
+	City string `monke:"city"`
+	Zip  int    `monke:"zip"`
+}
+
+type user struct {
+	Name     string   `monke:"name"`
+	Age      uint8    `monke:"age"`
+	Tags     []string `monke:"tags"`
+	Address  *address `monke:"address"`
+	Password string   `monke:"-"`
+	Admin    bool
+	internal int
+}
+
+func TestFromGo(t *testing.T) {
+	tests := []struct {
+		input    any
+		expected string
+	}{
+		{nil, "null"},
+		{true, "true"},
+		{42, "42"},
+		{uint16(7), "7"},
+		{1.5, "1.5"},
+		{float32(2), "2.0"},
+		{"monke", "monke"},
+		{[]int{1, 2, 3}, "[1, 2, 3]"},
+		{[2]bool{true, false}, "[true, false]"},
+		{[]string(nil), "null"},
+		{map[string]int{"b": 2, "a": 1}, "{a: 1, b: 2}"},
+		{map[int]string{1: "one"}, "{1: one}"},
+		{(*address)(nil), "null"},
+		{&address{City: "Ljubljana", Zip: 1000}, "{city: Ljubljana, zip: 1000}"},
+		{
+			user{Name: "ana", Age: 30, Tags: []string{"x"}, Password: "secret", Admin: true, internal: 1},
+			"{Admin: true, address: null, age: 30, name: ana, tags: [x]}",
+		},
+		{[]any{1, "a", nil}, "[1, a, null]"},
+		{&object.Integer{Value: 5}, "5"},
+	}
+	for _, tt := range tests {
+		obj, err := object.FromGo(tt.input)
+		require.NoError(t, err, "input %#v", tt.input)
+		assert.Equal(t, tt.expected, obj.Inspect(), "input %#v", tt.input)
+	}
+
+	b, err := object.FromGo(false)
+	require.NoError(t, err)
+	assert.Same(t, object.FALSE, b)
+}
+
+func TestFromGoErrors(t *testing.T) {
+	tests := []struct {
+		input    any
+		expected string
+	}{
+		{uint64(math.MaxUint64), "cannot convert uint64 18446744073709551615: out of range for INTEGER"},
+		{make(chan int), "cannot convert chan int to an object"},
+		{map[string]any{"c": complex(1, 2)}, "cannot convert complex128 to an object"},
+		{func() (int, int) { return 1, 2 }, "cannot convert func() (int, int): a func must return at most a value and an error"},
+	}
+	for _, tt := range tests {
+		_, err := object.FromGo(tt.input)
+		assert.EqualError(t, err, tt.expected)
+	}
+
+	type node struct{ Next *node }
+	cyclic := &node{}
+	cyclic.Next = cyclic
+	_, err := object.FromGo(cyclic)
+	assert.ErrorContains(t, err, "value nested too deeply")
+}
+
+func TestToGo(t *testing.T) {
+	source := &user{Name: "ana", Age: 30, Tags: []string{"x", "y"}, Address: &address{City: "Koper", Zip: 6000}}
+	obj, err := object.FromGo(source)
+	require.NoError(t, err)
+
+	var u user
+	require.NoError(t, object.ToGo(obj, &u))
+	assert.Equal(t, *source, u)
+
+	var native any
+	require.NoError(t, object.ToGo(obj, &native))
+	assert.Equal(t, map[string]any{
+		"name":    "ana",
+		"age":     int64(30),
+		"tags":    []any{"x", "y"},
+		"address": map[string]any{"city": "Koper", "zip": int64(6000)},
+		"Admin":   false,
+	}, native)
+
+	var m map[string]int
+	require.NoError(t, object.ToGo(mustFromGo(t, map[string]int{"a": 1}), &m))
+	assert.Equal(t, map[string]int{"a": 1}, m)
+
+	var f float64
+	require.NoError(t, object.ToGo(&object.Integer{Value: 3}, &f))
+	assert.Equal(t, 3.0, f)
+	require.NoError(t, object.ToGo(&object.Float{Value: 2.5}, &f))
+	assert.Equal(t, 2.5, f)
+	require.NoError(t, object.ToGo(&object.Float{Value: 2.5}, &native))
+	assert.Equal(t, 2.5, native)
+
+	var p *int
+	require.NoError(t, object.ToGo(object.NULL, &p))
+	assert.Nil(t, p)
+
+	var o object.Object
+	require.NoError(t, object.ToGo(&object.String{Value: "s"}, &o))
+	assert.Equal(t, &object.String{Value: "s"}, o)
+}
+
+func TestToGoErrors(t *testing.T) {
+	var i int
+	var i8 int8
+	var u uint
+	var s string
+	var ints []int
+	var pair [2]int
+	var u2 user
+	var f32 float32
+	tests := []struct {
+		obj      any
+		target   any
+		expected string
+	}{
+		{"x", &i, "cannot convert STRING to int"},
+		{300, &i8, "integer 300 out of range for int8"},
+		{-1, &u, "integer -1 out of range for uint"},
+		{1, &s, "cannot convert INTEGER to string"},
+		{1.5, &i, "cannot convert FLOAT to int"},
+		{1e300, &f32, "float 1e+300 out of range for float32"},
+		{[]any{1, "two"}, &ints, "[1]: cannot convert STRING to int"},
+		{[]int{1}, &pair, "cannot convert ARRAY of 1 elements to [2]int"},
+		{map[string]any{"address": map[string]any{"zip": "x"}}, &u2, "address.zip: cannot convert STRING to int"},
+		{1, i, "ToGo target must be a non-nil pointer, got int"},
+	}
+	for _, tt := range tests {
+		err := object.ToGo(mustFromGo(t, tt.obj), tt.target)
+		assert.EqualError(t, err, tt.expected)
+	}
+
+	var a any
+	assert.EqualError(t, object.ToGo(nil, &i), "cannot convert a nil Object")
+	assert.EqualError(t, object.ToGo(&object.Array{Elements: []object.Object{nil}}, &ints), "[0]: cannot convert a nil Object")
+	assert.EqualError(t, object.ToGo(&object.Array{Elements: []object.Object{nil}}, &a), "[0]: cannot convert a nil Object")
+}
+
+func TestFromGoFunc(t *testing.T) {
+	tests := []struct {
+		fn       any
+		args     []object.Object
+		expected string
+	}{
+		{func(a, b int) int { return a + b }, ints(1, 2), "3"},
+		{func() {}, nil, "null"},
+		{func(xs ...int) int { return len(xs) }, ints(1, 2, 3), "3"},
+		{func(sep string, xs ...int) []int { return xs }, []object.Object{&object.String{Value: ","}, &object.Integer{Value: 4}}, "[4]"},
+		{func(u user) string { return u.Name }, []object.Object{mustFromGo(t, map[string]any{"name": "ana"})}, "ana"},
+		{func(a int) int { return a }, ints(), "ERROR: wrong number of arguments. got=0, want=1"},
+		{func(a int, xs ...int) int { return a }, ints(), "ERROR: wrong number of arguments. got=0, want=1+"},
+		{func(a int) int { return a }, []object.Object{object.TRUE}, "ERROR: argument 1: cannot convert BOOLEAN to int"},
+		{func() (int, error) { return 0, errors.New("boom") }, nil, "ERROR: boom"},
+		{func() error { return nil }, nil, "null"},
+		{func() chan int { return nil }, nil, "ERROR: result: cannot convert chan int to an object"},
+	}
+	for _, tt := range tests {
+		obj, err := object.FromGo(tt.fn)
+		require.NoError(t, err)
+		builtin, ok := obj.(*object.Builtin)
+		require.True(t, ok, "got %T", obj)
+		assert.Equal(t, tt.expected, builtin.Fn(tt.args...).Inspect())
+	}
+
+	obj, _ := object.FromGo(func() error { return errors.New("boom") })
+	errObj := obj.(*object.Builtin).Fn().(*object.Error)
+	assert.Equal(t, object.RuntimeError, errObj.Kind)
+
+	obj, _ = object.FromGo(strings.ToUpper)
+	assert.Equal(t, "strings.ToUpper", obj.(*object.Builtin).Name)
+
+	obj, _ = object.FromGo(func(xs ...int) int { return xs[1] })
+	errObj = obj.(*object.Builtin).Fn(ints(1)...).(*object.Error)
+	assert.Equal(t, object.RuntimeError, errObj.Kind)
+	assert.Contains(t, errObj.Message, "panicked: runtime error: index out of range")
+}
+
+func mustFromGo(t *testing.T, value any) object.Object {
+	obj, err := object.FromGo(value)
+	require.NoError(t, err)
+	return obj
+}
+
+func ints(values ...int64) []object.Object {
+	objs := make([]object.Object, len(values))
+	for i, v := range values {
+		objs[i] = &object.Integer{Value: v}
+	}
+	return objs
+}
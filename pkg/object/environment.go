@@ -1,13 +1,23 @@
 package object
 
+import (
+	"sort"
+	"sync/atomic"
+)
+
 func NewEnvironment() *Environment {
 	s := make(map[string]Object)
 	return &Environment{store: s, outer: nil}
 }
 
+// Environment maps names to values. It is not safe for concurrent use while
+// names are being set. Once frozen it is read-only and may be shared between
+// goroutines, typically as the outer environment of one environment per
+// script.
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store  map[string]Object
+	outer  *Environment
+	frozen atomic.Bool
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
@@ -17,11 +27,45 @@ func (e *Environment) Get(name string) (Object, bool) {
 	}
 	return obj, ok
 }
+
+// Set binds name in this environment. It panics if the environment is
+// frozen.
 func (e *Environment) Set(name string, val Object) Object {
+	if e.frozen.Load() {
+		panic("object: Set on frozen environment")
+	}
 	e.store[name] = val
 	return val
 }
 
+// Freeze makes the environment and the environments enclosing it
+// read-only. Freezing an environment again, from any goroutine, is a no-op.
+func (e *Environment) Freeze() {
+	for env := e; env != nil; env = env.outer {
+		env.frozen.Store(true)
+	}
+}
+
+// Frozen reports whether Freeze has been called on the environment.
+func (e *Environment) Frozen() bool {
+	return e.frozen.Load()
+}
+
+// Outer returns the enclosing environment, or nil for a global environment.
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
+
+// Names returns the names bound in this environment itself, sorted.
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
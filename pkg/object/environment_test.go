@@ -0,0 +1,31 @@
+package object_test
+
+import (
+	"github.com/muter3000/monkeparser/pkg/object"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEnvironmentFreeze(t *testing.T) {
+	outer := object.NewEnvironment()
+	outer.Set("a", &object.Integer{Value: 1})
+	inner := object.NewEnclosedEnvironment(outer)
+	inner.Set("b", &object.Integer{Value: 2})
+
+	inner.Freeze()
+	assert.True(t, inner.Frozen())
+	assert.True(t, outer.Frozen())
+
+	a, ok := inner.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "1", a.Inspect())
+	assert.PanicsWithValue(t, "object: Set on frozen environment", func() {
+		outer.Set("a", object.NULL)
+	})
+
+	script := object.NewEnclosedEnvironment(inner)
+	assert.False(t, script.Frozen())
+	script.Set("a", &object.Integer{Value: 3})
+	a, _ = script.Get("a")
+	assert.Equal(t, "3", a.Inspect())
+}
@@ -4,6 +4,12 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/muter3000/monkeparser/pkg/ast"
+	"github.com/muter3000/monkeparser/pkg/token"
+	"hash/fnv"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -11,11 +17,26 @@ type ObjectType string
 
 const (
 	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	NULL_OBJ         = "NULL"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
 	ERROR_OBJ        = "ERROR"
 	FUNCTION_OBJ     = "FUNCTION"
+	STRING_OBJ       = "STRING"
+	ARRAY_OBJ        = "ARRAY"
+	HASH_OBJ         = "HASH"
+	BUILTIN_OBJ      = "BUILTIN"
+	MODULE_OBJ       = "MODULE"
+	REGEX_OBJ        = "REGEX"
+)
+
+// NULL, TRUE and FALSE are the only values of their types. The evaluator
+// compares them by identity, so they must never be copied or modified.
+var (
+	NULL  = &Null{}
+	TRUE  = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
 )
 
 type Object interface {
@@ -35,6 +56,24 @@ func (i *Integer) Inspect() string {
 	return fmt.Sprintf("%d", i.Value)
 }
 
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType {
+	return FLOAT_OBJ
+}
+
+// Inspect prints the shortest representation that reads back as the same
+// float, keeping a fraction so that whole floats do not look like integers.
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eInN") {
+		s += ".0"
+	}
+	return s
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -64,31 +103,254 @@ type ReturnValue struct {
 func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
+// Frame records a function that an error propagated out of, along with the
+// position of the call that entered it.
+type Frame struct {
+	Function string
+	CallSite token.Position
+}
+
+// Error kinds let scripts tell failures apart when catching them.
+const (
+	RuntimeError    = "RuntimeError"
+	TypeError       = "TypeError"
+	NameError       = "NameError"
+	ArgumentError   = "ArgumentError"
+	MatchError      = "MatchError"
+	IOError         = "IOError"
+	RecursionError  = "RecursionError"
+	CancelledError  = "CancelledError"
+	LimitError      = "LimitError"
+	ImportError     = "ImportError"
+	PermissionError = "PermissionError" // The host has not granted a capability, such as file access
+	ThrownError     = "Error"           // Default kind of values raised with throw
+)
+
+// MaxStackFrames is how many frames an error keeps. Frames further out are
+// only counted, so that errors from deep recursion stay small.
+const MaxStackFrames = 20
+
 type Error struct {
+	Kind    string
 	Message string
+	Value   Object         // The thrown value, for errors raised by throw
+	Pos     token.Position // Where the error was raised
+	Stack   []Frame        // Innermost call first
+	Omitted int            // Frames left out of Stack beyond MaxStackFrames
+	Fatal   bool           // Fatal errors end the evaluation and cannot be caught
+}
+
+// NewError creates an error of the given kind. Builtins use it to raise
+// errors that scripts can catch and inspect.
+func NewError(kind string, format string, a ...interface{}) *Error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, a...)}
+}
+
+// PushFrame records that the error propagated out of another function.
+func (e *Error) PushFrame(frame Frame) {
+	if len(e.Stack) >= MaxStackFrames {
+		e.Omitted++
+		return
+	}
+	e.Stack = append(e.Stack, frame)
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
 func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
 
+// Traceback renders the error with the location of every frame on its call
+// stack, innermost first.
+func (e *Error) Traceback() string {
+	var out bytes.Buffer
+	out.WriteString(e.Inspect())
+	pos := e.Pos
+	for _, frame := range e.Stack {
+		writeTracebackLine(&out, frame.Function, pos)
+		pos = frame.CallSite
+	}
+	if e.Omitted > 0 {
+		fmt.Fprintf(&out, "\n    ... %d more frames", e.Omitted)
+		pos = token.Position{}
+	}
+	writeTracebackLine(&out, "<main>", pos)
+	return out.String()
+}
+
+func writeTracebackLine(out *bytes.Buffer, function string, pos token.Position) {
+	out.WriteString("\n    at ")
+	out.WriteString(function)
+	if pos.IsValid() {
+		out.WriteString(" (")
+		out.WriteString(pos.String())
+		out.WriteString(")")
+	}
+}
+
 type Function struct {
-	Parameters  []*ast.Identifier
+	Name        string
+	Parameters  []*ast.Parameter
+	Rest        *ast.Identifier
 	Body        *ast.BlockStatement
 	Environment *Environment
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
+
+// Source returns the function as a function literal, which parses back to
+// a function with the same parameters and body. The environment the
+// function closes over is not part of it.
+func (f *Function) Source() string {
+	literal := &ast.FunctionLiteral{
+		Token:      token.Token{Type: token.FUNCTION, Literal: "fn"},
+		Parameters: f.Parameters,
+		Rest:       f.Rest,
+		Body:       f.Body,
+	}
+	return literal.String()
+}
+
 func (f *Function) Inspect() string {
 	var out bytes.Buffer
-	var params []string
-	for _, p := range f.Parameters {
-		params = append(params, p.String())
-	}
 	out.WriteString("fn")
 	out.WriteString("(")
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(ast.ParametersString(f.Parameters, f.Rest))
 	out.WriteString(") {\n")
 	out.WriteString(f.Body.String())
 	out.WriteString("\n}")
 	return out.String()
 }
+
+type String struct {
+	Value string
+}
+
+func (s *String) Type() ObjectType { return STRING_OBJ }
+func (s *String) Inspect() string  { return s.Value }
+
+type Array struct {
+	Elements []Object
+}
+
+func (a *Array) Type() ObjectType { return ARRAY_OBJ }
+func (a *Array) Inspect() string {
+	var out bytes.Buffer
+	var elements []string
+	for _, e := range a.Elements {
+		elements = append(elements, e.Inspect())
+	}
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+	return out.String()
+}
+
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+// Hashable is implemented by objects that can be used as hash keys.
+type Hashable interface {
+	HashKey() HashKey
+}
+
+func (b *Boolean) HashKey() HashKey {
+	var value uint64
+	if b.Value {
+		value = 1
+	}
+	return HashKey{Type: b.Type(), Value: value}
+}
+
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+// HashKey hashes the bits of the float, with -0.0 as 0.0 and all NaNs as one
+// key. Floats and integers are different keys even when they compare equal,
+// so 1.0 and 1 do not find each other.
+func (f *Float) HashKey() HashKey {
+	value := f.Value
+	switch {
+	case value == 0:
+		value = 0
+	case math.IsNaN(value):
+		value = math.NaN()
+	}
+	return HashKey{Type: f.Type(), Value: math.Float64bits(value)}
+}
+
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+type Hash struct {
+	Pairs map[HashKey]HashPair
+}
+
+func (h *Hash) Type() ObjectType { return HASH_OBJ }
+
+// Inspect lists the pairs ordered by their printed keys so that the output
+// does not depend on map iteration order.
+func (h *Hash) Inspect() string {
+	var out bytes.Buffer
+	var pairs []string
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
+	}
+	sort.Strings(pairs)
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+	return out.String()
+}
+
+// Get looks up the value stored under the given string key.
+func (h *Hash) Get(key string) (Object, bool) {
+	pair, ok := h.Pairs[(&String{Value: key}).HashKey()]
+	if !ok {
+		return nil, false
+	}
+	return pair.Value, true
+}
+
+type BuiltinFunction func(args ...Object) Object
+
+// Builtin is a function implemented in Go. Parameters names the positional
+// parameters so that callers can pass them as named arguments; a builtin
+// without Parameters only accepts positional arguments.
+type Builtin struct {
+	Name       string
+	Parameters []string
+	Fn         BuiltinFunction
+}
+
+func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
+func (b *Builtin) Inspect() string {
+	return fmt.Sprintf("builtin %s(%s)", b.Name, strings.Join(b.Parameters, ", "))
+}
+
+// Module is an imported module. Scripts read its exports with member access.
+type Module struct {
+	Name    string
+	Exports map[string]Object
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string  { return fmt.Sprintf("<module %s>", m.Name) }
+
+// Regex is a compiled regular expression, so that scripts can compile a
+// pattern once and reuse it.
+type Regex struct {
+	Value *regexp.Regexp
+}
+
+func (r *Regex) Type() ObjectType { return REGEX_OBJ }
+func (r *Regex) Inspect() string  { return fmt.Sprintf("<regex %q>", r.Value.String()) }
@@ -10,12 +10,15 @@ import (
 
 const (
 	LOWEST int = iota + 1
+	TERNARY
+	NULLISH
 	EQUALS
 	LESSGREATER
 	SUM
 	PRODUCT
 	PREFIX
 	CALL
+	INDEX
 )
 
 type (
@@ -29,7 +32,8 @@ type Parser struct {
 	curToken  token.Token
 	peekToken token.Token
 
-	errors []string
+	errors   []string
+	warnings []string
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
@@ -39,6 +43,12 @@ func (p *Parser) Errors() []string {
 	return p.errors
 }
 
+// Warnings returns diagnostics that do not prevent the program from running,
+// such as unreachable match arms.
+func (p *Parser) Warnings() []string {
+	return p.warnings
+}
+
 func (p *Parser) NextToken() {
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
@@ -53,17 +63,24 @@ func New(l *lexer.Lexer) *Parser {
 	p := &Parser{l: l, errors: []string{}}
 
 	p.prefixParseFns = map[token.TokenType]prefixParseFn{
-		token.IDENT: p.parseIdentifier,
-		token.INT:   p.parseIntegerLiteral,
-		token.FALSE: p.parseBooleanLiteral,
-		token.TRUE:  p.parseBooleanLiteral,
-		token.SUB:   p.parsePrefixModifier,
-		token.BANG:  p.parsePrefixModifier,
-
-		token.LPAREN: p.parseGroupedExpression,
+		token.IDENT:  p.parseIdentifier,
+		token.INT:    p.parseIntegerLiteral,
+		token.FLOAT:  p.parseFloatLiteral,
+		token.STRING: p.parseStringLiteral,
+		token.FALSE:  p.parseBooleanLiteral,
+		token.TRUE:   p.parseBooleanLiteral,
+		token.NULL:   p.parseNullLiteral,
+		token.SUB:    p.parsePrefixModifier,
+		token.BANG:   p.parsePrefixModifier,
+
+		token.LPAREN:   p.parseGroupedExpression,
+		token.LBRACKET: p.parseArrayLiteral,
+		token.LBRACE:   p.parseHashLiteral,
 
 		token.IF:       p.parseIfExpression,
 		token.FUNCTION: p.parseFuncExpression,
+		token.MATCH:    p.parseMatchExpression,
+		token.TRY:      p.parseTryExpression,
 	}
 
 	p.infixParseFns = map[token.TokenType]infixParseFn{
@@ -78,7 +95,14 @@ func New(l *lexer.Lexer) *Parser {
 		token.MUL:    p.parseInfixExpression,
 		token.DIV:    p.parseInfixExpression,
 
-		token.LPAREN: p.parseCallExpression,
+		token.NULLISH:  p.parseInfixExpression,
+		token.QUESTION: p.parseConditionalExpression,
+
+		token.LPAREN:       p.parseCallExpression,
+		token.LBRACKET:     p.parseIndexExpression,
+		token.OPT_LBRACKET: p.parseIndexExpression,
+		token.DOT:          p.parseMemberExpression,
+		token.OPT_DOT:      p.parseMemberExpression,
 	}
 
 	p.NextToken()
@@ -112,11 +136,14 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	ls := &ast.LetStatement{Token: p.curToken}
-	if !p.expectPeek(token.IDENT) {
+	if !p.expectBindingPattern() {
 		return nil
 	}
 
-	ls.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	ls.Name = p.parsePattern()
+	if ls.Name == nil {
+		return nil
+	}
 
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
@@ -124,7 +151,15 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	p.NextToken()
 
 	ls.Value = p.parseExpression(LOWEST)
-	p.NextToken()
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.NextToken()
+	}
+
+	if fl, ok := ls.Value.(*ast.FunctionLiteral); ok {
+		if ident, ok := ls.Name.(*ast.Identifier); ok {
+			fl.Name = ident.Value
+		}
+	}
 
 	return ls
 }
@@ -135,18 +170,71 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 
 	if !p.curTokenIs(token.SEMICOLON) {
 		rs.ReturnValue = p.parseExpression(LOWEST)
-		p.NextToken()
+		if p.peekTokenIs(token.SEMICOLON) {
+			p.NextToken()
+		}
 	}
 
 	return rs
 }
 
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	ts := &ast.ThrowStatement{Token: p.curToken}
+	p.NextToken()
+
+	ts.Value = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.NextToken()
+	}
+
+	return ts
+}
+
+func (p *Parser) parseImportStatement() *ast.ImportStatement {
+	is := &ast.ImportStatement{Token: p.curToken}
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+	is.Path = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.AS) {
+		return nil
+	}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	is.Alias = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.NextToken()
+	}
+	return is
+}
+
+func (p *Parser) parseExportStatement() *ast.ExportStatement {
+	es := &ast.ExportStatement{Token: p.curToken}
+	if !p.expectPeek(token.LET) {
+		return nil
+	}
+	es.Let = p.parseLetStatement()
+	if es.Let == nil {
+		return nil
+	}
+	return es
+}
+
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
 	case token.LET:
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
+	case token.IMPORT:
+		return p.parseImportStatement()
+	case token.EXPORT:
+		return p.parseExportStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -179,6 +267,14 @@ var precedences = map[token.TokenType]int{
 	token.MUL:    PRODUCT,
 	token.DIV:    PRODUCT,
 	token.LPAREN: CALL,
+
+	token.NULLISH:  NULLISH,
+	token.QUESTION: TERNARY,
+
+	token.LBRACKET:     INDEX,
+	token.OPT_LBRACKET: INDEX,
+	token.DOT:          INDEX,
+	token.OPT_DOT:      INDEX,
 }
 
 func (p *Parser) peekPrecedence() int {
@@ -232,6 +328,20 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	return &ast.IntegerLiteral{Token: p.curToken, Value: val}
 }
 
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	val, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		msg := fmt.Sprintf("could not parse %q as float", p.curToken.Literal)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+	return &ast.FloatLiteral{Token: p.curToken, Value: val}
+}
+
+func (p *Parser) parseStringLiteral() ast.Expression {
+	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
 	stmt.Expression = p.parseExpression(LOWEST)
@@ -278,6 +388,10 @@ func (p *Parser) parseBooleanLiteral() ast.Expression {
 	}
 }
 
+func (p *Parser) parseNullLiteral() ast.Expression {
+	return &ast.NullLiteral{Token: p.curToken}
+}
+
 func (p *Parser) parseGroupedExpression() ast.Expression {
 	p.NextToken()
 
@@ -324,9 +438,53 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return exp
 }
 
+func (p *Parser) parseTryExpression() ast.Expression {
+	exp := &ast.TryExpression{Token: p.curToken}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	exp.Block = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.NextToken()
+		if p.peekTokenIs(token.LPAREN) {
+			p.NextToken()
+			if !p.expectBindingPattern() {
+				return nil
+			}
+			exp.CatchParam = p.parsePattern()
+			if exp.CatchParam == nil || !p.expectPeek(token.RPAREN) {
+				return nil
+			}
+		}
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		exp.Catch = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.NextToken()
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		exp.Finally = p.parseBlockStatement()
+	}
+
+	if exp.Catch == nil && exp.Finally == nil {
+		p.errors = append(p.errors, "expected catch or finally after try block")
+		return nil
+	}
+	return exp
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
-	bExp := &ast.BlockStatement{Statements: []ast.Statement{}}
+	bExp := &ast.BlockStatement{Token: p.curToken, Statements: []ast.Statement{}}
 	for !p.peekTokenIs(token.RBRACE) {
+		if p.peekTokenIs(token.EOF) {
+			p.peekError(token.RBRACE)
+			return bExp
+		}
 		p.NextToken()
 		bExp.Statements = append(bExp.Statements, p.parseStatement())
 	}
@@ -343,7 +501,7 @@ func (p *Parser) parseFuncExpression() ast.Expression {
 		return nil
 	}
 
-	fExpr.Parameters = p.parseFunctionParameters()
+	fExpr.Parameters, fExpr.Rest = p.parseFunctionParameters()
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
@@ -352,23 +510,70 @@ func (p *Parser) parseFuncExpression() ast.Expression {
 	return fExpr
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	var pars []*ast.Identifier
-	p.NextToken()
-	for p.peekTokenIs(token.COMMA) {
-		pars = append(pars, p.parseIdentifier().(*ast.Identifier))
-		p.NextToken()
+func (p *Parser) parseFunctionParameters() ([]*ast.Parameter, *ast.Identifier) {
+	var pars []*ast.Parameter
+	var rest *ast.Identifier
+	if p.peekTokenIs(token.RPAREN) {
 		p.NextToken()
+		return pars, nil
 	}
 
-	if !p.curTokenIs(token.RPAREN) {
-		pars = append(pars, p.parseIdentifier().(*ast.Identifier))
-		if !p.expectPeek(token.RPAREN) {
-			return nil
+	for {
+		if p.peekTokenIs(token.ELLIPSIS) {
+			p.NextToken()
+			if !p.expectPeek(token.IDENT) {
+				return nil, nil
+			}
+			rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			break
+		}
+		param := p.parseFunctionParameter()
+		if param == nil {
+			return nil, nil
 		}
+		if param.Default == nil && len(pars) > 0 && pars[len(pars)-1].Default != nil {
+			msg := fmt.Sprintf("required parameter %s follows parameter with default value",
+				param.Pattern.String())
+			p.errors = append(p.errors, msg)
+			return nil, nil
+		}
+		pars = append(pars, param)
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.NextToken()
 	}
 
-	return pars
+	if !p.expectPeek(token.RPAREN) {
+		return nil, nil
+	}
+	return pars, rest
+}
+
+func (p *Parser) parseFunctionParameter() *ast.Parameter {
+	if !p.expectBindingPattern() {
+		return nil
+	}
+	param := &ast.Parameter{Pattern: p.parsePattern()}
+	if param.Pattern == nil {
+		return nil
+	}
+	if p.peekTokenIs(token.ASSIGN) {
+		p.NextToken()
+		p.NextToken()
+		param.Default = p.parseExpression(LOWEST)
+	}
+	return param
+}
+
+// expectBindingPattern advances onto the start of a let or parameter
+// binding: an identifier or an array or hash destructuring pattern.
+func (p *Parser) expectBindingPattern() bool {
+	if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) {
+		p.NextToken()
+		return true
+	}
+	return p.expectPeek(token.IDENT)
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
@@ -387,15 +592,274 @@ func (p *Parser) parseCallArguments() []ast.Expression {
 		p.NextToken()
 		return args
 	}
+	named := false
+	for {
+		p.NextToken()
+		arg := p.parseCallArgument()
+		if _, ok := arg.(*ast.NamedArgument); ok {
+			named = true
+		} else if named {
+			p.errors = append(p.errors, "positional argument follows named argument")
+			return nil
+		}
+		args = append(args, arg)
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.NextToken()
+	}
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	return args
+}
+
+func (p *Parser) parseCallArgument() ast.Expression {
+	if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.COLON) {
+		arg := &ast.NamedArgument{
+			Token: p.curToken,
+			Name:  &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal},
+		}
+		p.NextToken()
+		p.NextToken()
+		arg.Value = p.parseExpression(LOWEST)
+		return arg
+	}
+	if p.curTokenIs(token.ELLIPSIS) {
+		spread := &ast.SpreadExpression{Token: p.curToken}
+		p.NextToken()
+		spread.Value = p.parseExpression(LOWEST)
+		return spread
+	}
+	return p.parseExpression(LOWEST)
+}
+
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	var list []ast.Expression
+	if p.peekTokenIs(end) {
+		p.NextToken()
+		return list
+	}
 	p.NextToken()
-	args = append(args, p.parseExpression(LOWEST))
+	list = append(list, p.parseExpression(LOWEST))
 	for p.peekTokenIs(token.COMMA) {
 		p.NextToken()
 		p.NextToken()
-		args = append(args, p.parseExpression(LOWEST))
+		list = append(list, p.parseExpression(LOWEST))
+	}
+	if !p.expectPeek(end) {
+		return nil
+	}
+	return list
+}
+
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	return &ast.ArrayLiteral{
+		Token:    p.curToken,
+		Elements: p.parseExpressionList(token.RBRACKET),
+	}
+}
+
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{Token: p.curToken}
+	for !p.peekTokenIs(token.RBRACE) {
+		p.NextToken()
+		key := p.parseExpression(LOWEST)
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+		p.NextToken()
+		value := p.parseExpression(LOWEST)
+		hash.Pairs = append(hash.Pairs, ast.HashLiteralPair{Key: key, Value: value})
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	return hash
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{
+		Token:    p.curToken,
+		Left:     left,
+		Optional: p.curTokenIs(token.OPT_LBRACKET),
+	}
+	p.NextToken()
+	exp.Index = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
 	}
+	return exp
+}
+
+func (p *Parser) parseMemberExpression(object ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{
+		Token:    p.curToken,
+		Object:   object,
+		Optional: p.curTokenIs(token.OPT_DOT),
+	}
+	// Keywords are allowed as property names, as in re.match.
+	if token.LookupIdent(p.peekToken.Literal) != p.peekToken.Type {
+		p.peekError(token.IDENT)
+		return nil
+	}
+	p.NextToken()
+	exp.Property = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	return exp
+}
+
+func (p *Parser) parseConditionalExpression(condition ast.Expression) ast.Expression {
+	exp := &ast.ConditionalExpression{Token: p.curToken, Condition: condition}
+	p.NextToken()
+	exp.Consequence = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+	p.NextToken()
+	// Parsing the alternative at LOWEST makes the operator right-associative.
+	exp.Alternative = p.parseExpression(LOWEST)
+	return exp
+}
+
+func (p *Parser) parseMatchExpression() ast.Expression {
+	exp := &ast.MatchExpression{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.NextToken()
+	exp.Subject = p.parseExpression(LOWEST)
 	if !p.expectPeek(token.RPAREN) {
 		return nil
 	}
-	return args
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	var catchAll ast.Pattern
+	for !p.peekTokenIs(token.RBRACE) {
+		p.NextToken()
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		if catchAll != nil {
+			msg := fmt.Sprintf("unreachable match arm %s after catch-all pattern %s",
+				arm.Pattern.String(), catchAll.String())
+			p.warnings = append(p.warnings, msg)
+		} else if _, ok := arm.Pattern.(*ast.Identifier); ok && arm.Guard == nil {
+			catchAll = arm.Pattern
+		}
+		exp.Arms = append(exp.Arms, arm)
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	return exp
+}
+
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{Pattern: p.parsePattern()}
+	if arm.Pattern == nil {
+		return nil
+	}
+	if p.peekTokenIs(token.IF) {
+		p.NextToken()
+		p.NextToken()
+		arm.Guard = p.parseExpression(LOWEST)
+	}
+	if !p.expectPeek(token.FAT_ARROW) {
+		return nil
+	}
+	arm.Token = p.curToken
+	p.NextToken()
+	arm.Body = p.parseExpression(LOWEST)
+	return arm
+}
+
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.curToken.Type {
+	case token.IDENT:
+		return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	case token.INT, token.FLOAT, token.STRING, token.TRUE, token.FALSE, token.NULL, token.SUB:
+		lp := &ast.LiteralPattern{Token: p.curToken}
+		lp.Value = p.parseExpression(PREFIX)
+		if lp.Value == nil {
+			return nil
+		}
+		return lp
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	case token.LBRACE:
+		return p.parseHashPattern()
+	default:
+		msg := fmt.Sprintf("expected pattern, got %s instead", p.curToken.Type)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+}
+
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	ap := &ast.ArrayPattern{Token: p.curToken}
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.NextToken()
+		if p.curTokenIs(token.DOTDOT) {
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			ap.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			break
+		}
+		el := p.parsePattern()
+		if el == nil {
+			return nil
+		}
+		ap.Elements = append(ap.Elements, el)
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	return ap
+}
+
+func (p *Parser) parseHashPattern() ast.Pattern {
+	hp := &ast.HashPattern{Token: p.curToken}
+	for !p.peekTokenIs(token.RBRACE) {
+		p.NextToken()
+		if !p.curTokenIs(token.IDENT) && !p.curTokenIs(token.STRING) {
+			msg := fmt.Sprintf("expected hash pattern key, got %s instead", p.curToken.Type)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		pair := ast.HashPatternPair{Key: p.curToken.Literal}
+		if p.peekTokenIs(token.COLON) {
+			p.NextToken()
+			p.NextToken()
+			pair.Value = p.parsePattern()
+			if pair.Value == nil {
+				return nil
+			}
+		} else if p.curTokenIs(token.IDENT) {
+			pair.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		} else {
+			p.peekError(token.COLON)
+			return nil
+		}
+		hp.Pairs = append(hp.Pairs, pair)
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	return hp
 }
@@ -66,8 +66,13 @@ func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 		t.Errorf("s not *ast.LetStatement. got=%T", s)
 		return false
 	}
-	if letStmt.Name.Value != name {
-		t.Errorf("letStmt.Name.Value not '%s'. got=%s", name, letStmt.Name.Value)
+	ident, ok := letStmt.Name.(*ast.Identifier)
+	if !ok {
+		t.Errorf("letStmt.Name not *ast.Identifier. got=%T", letStmt.Name)
+		return false
+	}
+	if ident.Value != name {
+		t.Errorf("letStmt.Name.Value not '%s'. got=%s", name, ident.Value)
 		return false
 	}
 	if letStmt.Name.TokenLiteral() != name {
@@ -176,7 +181,7 @@ func TestParseWhole(t *testing.T) {
 		t.Fatalf("ParseProgram() returned nil")
 	}
 
-	assert.Equal(t, "let x = 5;let y = 10;let foobar = 838383;if(true){ 2; }else{ 3;return; };let a = fn(a, b){ (a + b); };let b = add(a, b);return 5;", program.String())
+	assert.Equal(t, "let x = 5;let y = 10;let foobar = 838383;if(true){ 2; }else{ 3;return; }let a = fn(a, b){ (a + b); };let b = add(a, b);return 5;", program.String())
 }
 
 func TestCallExpressionParsing(t *testing.T) {
@@ -298,6 +303,34 @@ func TestIntegerExpression(t *testing.T) {
 	}
 }
 
+func TestFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"2.5;", 2.5},
+		{"1e3", 1000},
+		{"0.125E-1", 0.0125},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+		}
+		assert.Equal(t, tt.expected, literal.Value)
+	}
+
+	p := parser.New(lexer.New("1e999"))
+	p.ParseProgram()
+	assert.Equal(t, []string{`could not parse "1e999" as float`}, p.Errors())
+}
+
 func TestBooleanExpression(t *testing.T) {
 	input := "true;false;"
 	l := lexer.New(input)
@@ -344,6 +377,32 @@ func TestBooleanExpression(t *testing.T) {
 	}
 }
 
+func TestNullExpression(t *testing.T) {
+	input := "null;"
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+	null, ok := stmt.Expression.(*ast.NullLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.NullLiteral. got=%T", stmt.Expression)
+	}
+	if null.TokenLiteral() != "null" {
+		t.Errorf("null.TokenLiteral not %s. got=%s", "null",
+			null.TokenLiteral())
+	}
+}
+
 func TestFunctionLiteralParsing(t *testing.T) {
 	input := `fn(x, y) { x + y; }`
 	l := lexer.New(input)
@@ -368,8 +427,8 @@ func TestFunctionLiteralParsing(t *testing.T) {
 		t.Fatalf("function literal parameters wrong. want 2, got=%d\n",
 			len(function.Parameters))
 	}
-	testLiteralExpression(t, function.Parameters[0], "x")
-	testLiteralExpression(t, function.Parameters[1], "y")
+	testLiteralExpression(t, function.Parameters[0].Pattern.(*ast.Identifier), "x")
+	testLiteralExpression(t, function.Parameters[1].Pattern.(*ast.Identifier), "y")
 	if len(function.Body.Statements) != 1 {
 		t.Fatalf("function.Body.Statements has not 1 statements. got=%d\n",
 			len(function.Body.Statements))
@@ -460,6 +519,38 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"2 * (2 + 3) + 4",
 			"((2 * (2 + 3)) + 4)",
 		},
+		{
+			"a ?? b == null",
+			"(a ?? (b == null))",
+		},
+		{
+			"a ?? b ?? c + 1",
+			"((a ?? b) ?? (c + 1))",
+		},
+		{
+			"a * [1, 2, 3, 4][b * c] * d",
+			"((a * ([1, 2, 3, 4][(b * c)])) * d)",
+		},
+		{
+			"add(a * b[2], b[1], 2 * [1, 2][1])",
+			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
+		},
+		{
+			"a.b.c(d)?.e?[f]",
+			"((((a.b).c)(d)?.e)?[f])",
+		},
+		{
+			"re.match(p, s) == x?.if",
+			"((re.match)(p, s) == (x?.if))",
+		},
+		{
+			"a ? b : c ? d : e",
+			"(a ? b : (c ? d : e))",
+		},
+		{
+			"a == b ? c ?? d : e + f",
+			"((a == b) ? (c ?? d) : (e + f))",
+		},
 	}
 
 	for _, tt := range tests {
@@ -738,3 +829,348 @@ func TestParsingInfixExpressions(t *testing.T) {
 		}
 	}
 }
+
+func TestStringLiteralExpression(t *testing.T) {
+	input := `"hello \"world\"";`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+	}
+	assert.Equal(t, `hello "world"`, literal.Value)
+	assert.Equal(t, `"hello \"world\""`, literal.String())
+}
+
+func TestParsingArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+	}
+	if len(array.Elements) != 3 {
+		t.Fatalf("len(array.Elements) not 3. got=%d", len(array.Elements))
+	}
+	testIntegerLiteral(t, array.Elements[0], 1)
+	testInfixExpression(t, array.Elements[1], 2, "*", 2)
+	testInfixExpression(t, array.Elements[2], 3, "+", 3)
+}
+
+func TestParsingHashLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{}`, `{}`},
+		{`{"one": 1, "two": 2}`, `{"one": 1, "two": 2}`},
+		{`{"one": 0 + 1, true: 2, 3: "three"}`, `{"one": (0 + 1), true: 2, 3: "three"}`},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		hash, ok := stmt.Expression.(*ast.HashLiteral)
+		if !ok {
+			t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+		}
+		assert.Equal(t, tt.expected, hash.String())
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	input := `
+		match (x) {
+			0 => "zero",
+			-1 => "minus one",
+			[head, ..tail] if head > 0 => head,
+			{name, "age": [a, _]} => name,
+			n => n,
+		}`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("exp is not ast.MatchExpression. got=%T", stmt.Expression)
+	}
+	testIdentifier(t, exp.Subject, "x")
+	if len(exp.Arms) != 5 {
+		t.Fatalf("match has wrong number of arms. got=%d", len(exp.Arms))
+	}
+
+	patterns := []string{"0", "-1", "[head, ..tail]", `{name, "age": [a, _]}`, "n"}
+	for i, pattern := range patterns {
+		assert.Equal(t, pattern, exp.Arms[i].Pattern.String())
+	}
+	if _, ok := exp.Arms[2].Pattern.(*ast.ArrayPattern); !ok {
+		t.Errorf("arm 2 pattern is not ast.ArrayPattern. got=%T", exp.Arms[2].Pattern)
+	}
+	testInfixExpression(t, exp.Arms[2].Guard, "head", ">", 0)
+	if _, ok := exp.Arms[3].Pattern.(*ast.HashPattern); !ok {
+		t.Errorf("arm 3 pattern is not ast.HashPattern. got=%T", exp.Arms[3].Pattern)
+	}
+	testIdentifier(t, exp.Arms[4].Body, "n")
+	assert.Empty(t, p.Warnings())
+}
+
+func TestMatchUnreachableArmWarnings(t *testing.T) {
+	input := `
+		match (x) {
+			n if n > 1 => 1,
+			_ => 2,
+			3 => 3,
+			y => 4
+		}`
+	l := lexer.New(input)
+	p := parser.New(l)
+	p.ParseProgram()
+	checkParserErrors(t, p)
+
+	warnings := p.Warnings()
+	assert.Equal(t, 2, len(warnings))
+	assert.Equal(t, "unreachable match arm 3 after catch-all pattern _", warnings[0])
+	assert.Equal(t, "unreachable match arm y after catch-all pattern _", warnings[1])
+}
+
+func TestMatchPatternErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match (x) { + => 1 }", "expected pattern, got + instead"},
+		{"match (x) { {1: a} => 1 }", "expected hash pattern key, got INT instead"},
+		{"match (x) { [a, ..] => 1 }", "expected next token to be 'IDENT', got ] instead"},
+		{"match (x) { a 1 }", "expected next token to be '=>', got INT instead"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("parser.Errors() returned no errors for %q", tt.input)
+			continue
+		}
+		assert.Equal(t, tt.expected, errors[0])
+	}
+}
+
+func TestDestructuringParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b, ..rest] = arr;", "let [a, b, ..rest] = arr;"},
+		{"let {name, age} = person;", "let {name, age} = person;"},
+		{`let {"first name": first, tags: [tag]} = person;`, `let {"first name": first, "tags": [tag]} = person;`},
+		{"let f = fn([x, y], {z}, w) { x };", "let f = fn([x, y], {z}, w){ x; };"},
+		{"let f = fn() { 1 };", "let f = fn(){ 1; };"},
+		{"let f = fn(x, y = 10) { x };", "let f = fn(x, y = 10){ x; };"},
+		{"let f = fn(x, [y] = [1 + 1], ...rest) { x };", "let f = fn(x, [y] = [(1 + 1)], ...rest){ x; };"},
+		{"let f = fn(...rest) { rest };", "let f = fn(...rest){ rest; };"},
+		{"f(1, ...xs, ...[2, 3])", "f(1, ...xs, ...[2, 3])"},
+		{"deploy(3, canary: true, region: a ? b : c)", "deploy(3, canary: true, region: (a ? b : c))"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		assert.Equal(t, tt.expected, program.String())
+	}
+}
+
+func TestDestructuringParsingErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, + ] = arr;", "expected pattern, got + instead"},
+		{"let f = fn(1) { 1 };", "expected next token to be 'IDENT', got INT instead"},
+		{"let f = fn(a b) { 1 };", "expected next token to be ')', got IDENT instead"},
+		{"let f = fn(a = 1, b) { 1 };", "required parameter b follows parameter with default value"},
+		{"let f = fn(...a, b) { 1 };", "expected next token to be ')', got , instead"},
+		{"let f = fn(...[a]) { 1 };", "expected next token to be 'IDENT', got [ instead"},
+		{"f(a: 1, 2)", "positional argument follows named argument"},
+		{"f(a: 1, ...b)", "positional argument follows named argument"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("parser.Errors() returned no errors for %q", tt.input)
+			continue
+		}
+		assert.Equal(t, tt.expected, errors[0])
+	}
+}
+
+func TestFunctionLiteralName(t *testing.T) {
+	input := "let myFunction = fn() { }; let [f] = [fn() { }]; fn() { };"
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	named := program.Statements[0].(*ast.LetStatement).Value.(*ast.FunctionLiteral)
+	assert.Equal(t, "myFunction", named.Name)
+	destructured := program.Statements[1].(*ast.LetStatement).Value.(*ast.ArrayLiteral)
+	assert.Equal(t, "", destructured.Elements[0].(*ast.FunctionLiteral).Name)
+	anonymous := program.Statements[2].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	assert.Equal(t, "", anonymous.Name)
+}
+
+func TestNodePositions(t *testing.T) {
+	input := "let x = 1;\nadd(x, 2 * 3)"
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	assert.Equal(t, token.Position{Line: 1, Column: 1}, program.Statements[0].Pos())
+	call := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+	assert.Equal(t, token.Position{Line: 2, Column: 1}, call.Pos())
+	assert.Equal(t, token.Position{Line: 2, Column: 10}, call.Arguments[1].Pos())
+}
+
+func TestReturnWithoutSemicolon(t *testing.T) {
+	l := lexer.New("fn() { return 1 }; 2")
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	assert.Len(t, program.Statements, 2)
+	assert.Equal(t, "fn(){ return 1; }2", program.String())
+}
+
+func TestLetWithoutSemicolon(t *testing.T) {
+	l := lexer.New("let x = 1\nlet y = x\ny")
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	assert.Len(t, program.Statements, 3)
+	assert.Equal(t, "let x = 1;let y = x;y", program.String())
+}
+
+func TestUnterminatedBlock(t *testing.T) {
+	tests := []string{
+		"fn() { return 1 ",
+		"if (x) { y",
+		"let f = fn(x) {",
+	}
+	for _, input := range tests {
+		l := lexer.New(input)
+		p := parser.New(l)
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("parser.Errors() returned no errors for %q", input)
+			continue
+		}
+		assert.Equal(t, "expected next token to be '}', got EOF instead", errors[0])
+	}
+}
+
+func TestTryExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { f() } catch (e) { e }", "try{ f(); }catch(e){ e; }"},
+		{"try { f() } catch { 0 }", "try{ f(); }catch{ 0; }"},
+		{"try { f() } finally { g() }", "try{ f(); }finally{ g(); }"},
+		{"let x = try { f() } catch ({message}) { message } finally { g() };", "let x = try{ f(); }catch({message}){ message; }finally{ g(); };"},
+		{`throw "boom";`, `throw "boom";`},
+		{`throw {"kind": "IOError"}`, `throw {"kind": "IOError"};`},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		assert.Equal(t, tt.expected, program.String())
+	}
+}
+
+func TestTryExpressionParsingErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { f() }", "expected catch or finally after try block"},
+		{"try { f() } catch (1) { }", "expected next token to be 'IDENT', got INT instead"},
+		{"try { f() } catch (e { }", "expected next token to be ')', got { instead"},
+		{"try f()", "expected next token to be '{', got IDENT instead"},
+		{"try { return 1 ", "expected next token to be '}', got EOF instead"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("parser.Errors() returned no errors for %q", tt.input)
+			continue
+		}
+		assert.Equal(t, tt.expected, errors[0])
+	}
+}
+
+func TestModuleStatementParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "lib/math.mk" as math`, `import "lib/math.mk" as math;`},
+		{`import "a.mk" as a; a.f()`, `import "a.mk" as a;(a.f)()`},
+		{"export let x = 1;", "export let x = 1;"},
+		{"export let [a, b] = pair", "export let [a, b] = pair;"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		assert.Equal(t, tt.expected, program.String())
+	}
+}
+
+func TestModuleStatementParsingErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"import lib as lib", "expected next token to be 'STRING', got IDENT instead"},
+		{`import "lib.mk"`, "expected next token to be 'AS', got EOF instead"},
+		{`import "lib.mk" as "lib"`, "expected next token to be 'IDENT', got STRING instead"},
+		{"export fn() {}", "expected next token to be 'LET', got FUNCTION instead"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("parser.Errors() returned no errors for %q", tt.input)
+			continue
+		}
+		assert.Equal(t, tt.expected, errors[0])
+	}
+}
@@ -2,54 +2,156 @@ package repl
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"fmt"
+	"github.com/muter3000/monkeparser/pkg/ast"
 	"github.com/muter3000/monkeparser/pkg/evaluator"
 	"github.com/muter3000/monkeparser/pkg/lexer"
 	"github.com/muter3000/monkeparser/pkg/object"
 	"github.com/muter3000/monkeparser/pkg/parser"
+	"github.com/muter3000/monkeparser/pkg/snapshot"
+	"github.com/muter3000/monkeparser/pkg/vfs"
 	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"strings"
 )
 
 type Repl struct {
-	input  io.Reader
+	input  *bufio.Reader // Shared with read_line in scripts
 	output io.Writer
 
 	prompt string
+
+	fs      vfs.WriteFS               // Read by :load, :restore and imports, written by :save
+	modules map[string]*object.Module // Kept for the whole session
 }
 
 func New(input io.Reader, output io.Writer, prompt string) *Repl {
-	return &Repl{input: input, output: output, prompt: prompt}
+	return &Repl{
+		input:   bufio.NewReader(input),
+		output:  output,
+		prompt:  prompt,
+		fs:      vfs.WritableDir("."),
+		modules: make(map[string]*object.Module),
+	}
 }
 
 func (r *Repl) Start() {
-	scanner := bufio.NewScanner(r.input)
 	env := object.NewEnvironment()
 	for {
 		fmt.Printf(r.prompt)
-		scanned := scanner.Scan()
-		if !scanned {
+		line, err := r.input.ReadString('\n')
+		if err != nil && line == "" {
 			return
 		}
-		line := scanner.Text()
-		l := lexer.New(line)
-		p := parser.New(l)
-		program := p.ParseProgram()
-		if len(p.Errors()) != 0 {
-			printParserErrors(r.output, p.Errors())
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(line, ":") {
+			env = r.runCommand(line, env)
 			continue
 		}
-		evaluated := evaluator.Eval(program, env)
-		if evaluated != nil {
-			_, err := io.WriteString(r.output, evaluated.Inspect())
-			if err != nil {
-				panic(err)
-			}
-			_, err = io.WriteString(r.output, "\n")
-			if err != nil {
-				return
-			}
+		r.run(line, env)
+	}
+}
+
+// run evaluates src in env and prints the result.
+func (r *Repl) run(src string, env *object.Environment) {
+	l := lexer.New(src)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParserErrors(r.output, p.Errors())
+		return
+	}
+	printParserWarnings(r.output, p.Warnings())
+	evaluated := r.evalInterruptibly(program, env)
+	if errObj, ok := evaluated.(*object.Error); ok {
+		writeLine(r.output, errObj.Traceback())
+		return
+	}
+	if evaluated != nil {
+		writeLine(r.output, evaluated.Inspect())
+	}
+}
+
+// runCommand runs a REPL command such as ":save session.json" and returns the
+// environment to continue the session with. Files are named relative to the
+// session's filesystem, like the files scripts read.
+func (r *Repl) runCommand(line string, env *object.Environment) *object.Environment {
+	fields := strings.Fields(line)
+	switch {
+	case fields[0] == ":save" && len(fields) == 2:
+		if err := saveSession(r.fs, fields[1], env); err != nil {
+			writeLine(r.output, "error: "+err.Error())
+			return env
+		}
+		writeLine(r.output, "saved session to "+fields[1])
+	case fields[0] == ":restore" && len(fields) == 2:
+		restored, err := restoreSession(r.fs, fields[1], r.newEvaluator())
+		if err != nil {
+			writeLine(r.output, "error: "+err.Error())
+			return env
+		}
+		writeLine(r.output, "restored session from "+fields[1])
+		return restored
+	case fields[0] == ":load" && len(fields) == 2:
+		src, err := fs.ReadFile(r.fs, fields[1])
+		if err != nil {
+			writeLine(r.output, "error: "+err.Error())
+			return env
 		}
+		r.run(string(src), env)
+	default:
+		writeLine(r.output, "usage: :load FILE | :save FILE | :restore FILE")
+	}
+	return env
+}
+
+func saveSession(fsys vfs.WriteFS, name string, env *object.Environment) error {
+	var buf bytes.Buffer
+	if err := snapshot.Snapshot(&buf, env); err != nil {
+		return err
+	}
+	return fsys.WriteFile(name, buf.Bytes())
+}
+
+// restoreSession restores a saved session with e, which imports the modules
+// it refers to.
+func restoreSession(fsys fs.FS, name string, e *evaluator.Evaluator) (*object.Environment, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
 	}
+	defer f.Close()
+	return snapshot.RestoreWith(f, e)
+}
+
+func writeLine(out io.Writer, msg string) {
+	_, err := io.WriteString(out, msg+"\n")
+	if err != nil {
+		panic(err)
+	}
+}
+
+// evalInterruptibly evaluates program, cancelling the evaluation rather than
+// exiting when the user presses Ctrl-C.
+func (r *Repl) evalInterruptibly(program *ast.Program, env *object.Environment) object.Object {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return r.newEvaluator().EvalContext(ctx, program, env)
+}
+
+// newEvaluator returns an evaluator wired to the session's input, output,
+// filesystem and modules.
+func (r *Repl) newEvaluator() *evaluator.Evaluator {
+	e := evaluator.New()
+	e.Stdout = r.output
+	e.Stdin = r.input
+	e.FS = r.fs
+	e.Modules = r.modules
+	return e
 }
 
 func printParserErrors(out io.Writer, errors []string) {
@@ -68,3 +170,12 @@ func printParserErrors(out io.Writer, errors []string) {
 		}
 	}
 }
+
+func printParserWarnings(out io.Writer, warnings []string) {
+	for _, msg := range warnings {
+		_, err := io.WriteString(out, "warning: "+msg+"\n")
+		if err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,362 @@
+// Package snapshot saves environments to a stable JSON format and restores
+// them, so that sessions can be resumed later.
+//
+// A snapshot lists every environment reachable from the saved one: the
+// environment itself, the environments enclosing it and the environments
+// captured by functions. Environments refer to each other by their index in
+// the list, which lets recursive closures refer back to the environment that
+// holds them. Functions are stored as their printed source and parsed again
+// on restore. Builtins are stored by name. Modules are stored by name and
+// imported again on restore, and regexes by their pattern.
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/muter3000/monkeparser/pkg/ast"
+	"github.com/muter3000/monkeparser/pkg/evaluator"
+	"github.com/muter3000/monkeparser/pkg/lexer"
+	"github.com/muter3000/monkeparser/pkg/object"
+	"github.com/muter3000/monkeparser/pkg/parser"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version is the version of the snapshot format written by Snapshot.
+const Version = 1
+
+type file struct {
+	Version      int           `json:"version"`
+	Environments []environment `json:"environments"` // The saved environment first
+}
+
+type environment struct {
+	Outer    *int      `json:"outer,omitempty"`
+	Bindings []binding `json:"bindings"`
+}
+
+type binding struct {
+	Name  string `json:"name"`
+	Value value  `json:"value"`
+}
+
+type value struct {
+	Type     object.ObjectType `json:"type"`
+	Integer  int64             `json:"integer,omitempty"`
+	Float    string            `json:"float,omitempty"` // Formatted so that infinities survive
+	Boolean  bool              `json:"boolean,omitempty"`
+	String   string            `json:"string,omitempty"` // Also the pattern of regexes
+	Elements []value           `json:"elements,omitempty"`
+	Pairs    []pair            `json:"pairs,omitempty"`
+	Name     string            `json:"name,omitempty"`   // Of functions, builtins and modules
+	Source   string            `json:"source,omitempty"` // Of functions
+	Env      *int              `json:"env,omitempty"`    // Captured by functions
+}
+
+type pair struct {
+	Key   value `json:"key"`
+	Value value `json:"value"`
+}
+
+// Snapshot writes env and everything reachable from it to w.
+func Snapshot(w io.Writer, env *object.Environment) error {
+	enc := &encoder{ids: make(map[*object.Environment]int)}
+	enc.envID(env)
+	for i := 0; i < len(enc.queue); i++ {
+		encoded, err := enc.encodeEnvironment(enc.queue[i])
+		if err != nil {
+			return err
+		}
+		enc.file.Environments = append(enc.file.Environments, encoded)
+	}
+	enc.file.Version = Version
+
+	out := json.NewEncoder(w)
+	out.SetIndent("", "  ")
+	out.SetEscapeHTML(false)
+	return out.Encode(enc.file)
+}
+
+type encoder struct {
+	file  file
+	ids   map[*object.Environment]int
+	queue []*object.Environment
+}
+
+// envID numbers environments in the order they are first reached.
+func (enc *encoder) envID(env *object.Environment) int {
+	if id, ok := enc.ids[env]; ok {
+		return id
+	}
+	id := len(enc.queue)
+	enc.ids[env] = id
+	enc.queue = append(enc.queue, env)
+	return id
+}
+
+func (enc *encoder) encodeEnvironment(env *object.Environment) (environment, error) {
+	encoded := environment{Bindings: []binding{}}
+	if outer := env.Outer(); outer != nil {
+		id := enc.envID(outer)
+		encoded.Outer = &id
+	}
+	for _, name := range env.Names() {
+		obj, _ := env.Get(name)
+		v, err := enc.encodeValue(obj)
+		if err != nil {
+			return environment{}, fmt.Errorf("cannot snapshot %s: %w", name, err)
+		}
+		encoded.Bindings = append(encoded.Bindings, binding{Name: name, Value: v})
+	}
+	return encoded, nil
+}
+
+func (enc *encoder) encodeValue(obj object.Object) (value, error) {
+	v := value{Type: obj.Type()}
+	switch obj := obj.(type) {
+	case *object.Null:
+	case *object.Boolean:
+		v.Boolean = obj.Value
+	case *object.Integer:
+		v.Integer = obj.Value
+	case *object.Float:
+		v.Float = strconv.FormatFloat(obj.Value, 'g', -1, 64)
+	case *object.String:
+		v.String = obj.Value
+	case *object.Regex:
+		v.String = obj.Value.String()
+	case *object.Array:
+		v.Elements = make([]value, len(obj.Elements))
+		for i, el := range obj.Elements {
+			encoded, err := enc.encodeValue(el)
+			if err != nil {
+				return value{}, err
+			}
+			v.Elements[i] = encoded
+		}
+	case *object.Hash:
+		pairs := make([]object.HashPair, 0, len(obj.Pairs))
+		for _, p := range obj.Pairs {
+			pairs = append(pairs, p)
+		}
+		sort.Slice(pairs, func(i, j int) bool {
+			a, b := pairs[i].Key, pairs[j].Key
+			if a.Type() != b.Type() {
+				return a.Type() < b.Type()
+			}
+			return a.Inspect() < b.Inspect()
+		})
+		for _, p := range pairs {
+			key, err := enc.encodeValue(p.Key)
+			if err != nil {
+				return value{}, err
+			}
+			val, err := enc.encodeValue(p.Value)
+			if err != nil {
+				return value{}, err
+			}
+			v.Pairs = append(v.Pairs, pair{Key: key, Value: val})
+		}
+	case *object.Function:
+		id := enc.envID(obj.Environment)
+		v.Name = obj.Name
+		v.Source = obj.Source()
+		v.Env = &id
+	case *object.Builtin:
+		if obj.Name == "" {
+			return value{}, fmt.Errorf("builtin has no name")
+		}
+		v.Name = obj.Name
+	case *object.Module:
+		v.Name = obj.Name
+	default:
+		return value{}, fmt.Errorf("unsupported value of type %s", obj.Type())
+	}
+	return v, nil
+}
+
+// Restore reads a snapshot written by Snapshot and returns the saved
+// environment. Builtins are looked up by name in builtins, or among the
+// default builtins if builtins is nil. Modules are imported with an
+// evaluator from evaluator.New, which has no filesystem and so can only
+// import native modules; use RestoreWith to import module files too.
+func Restore(r io.Reader, builtins map[string]*object.Builtin) (*object.Environment, error) {
+	e := evaluator.New()
+	if builtins != nil {
+		e.Builtins = builtins
+	}
+	return RestoreWith(r, e)
+}
+
+// RestoreWith is like Restore, but looks builtins up in e.Builtins and
+// imports modules with e, so that they come from its Loader or FS and are
+// shared through its Modules.
+func RestoreWith(r io.Reader, e *evaluator.Evaluator) (*object.Environment, error) {
+	var f file
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("cannot read snapshot: %w", err)
+	}
+	if f.Version != Version {
+		return nil, fmt.Errorf("unsupported snapshot version %d", f.Version)
+	}
+	if len(f.Environments) == 0 {
+		return nil, fmt.Errorf("snapshot has no environments")
+	}
+	dec := &decoder{evaluator: e, envs: make([]*object.Environment, len(f.Environments))}
+	for i := range f.Environments {
+		if err := dec.createEnvironment(f.Environments, i, 0); err != nil {
+			return nil, err
+		}
+	}
+	for i, env := range f.Environments {
+		for _, b := range env.Bindings {
+			obj, err := dec.decodeValue(b.Value)
+			if err != nil {
+				return nil, fmt.Errorf("cannot restore %s: %w", b.Name, err)
+			}
+			dec.envs[i].Set(b.Name, obj)
+		}
+	}
+	return dec.envs[0], nil
+}
+
+type decoder struct {
+	evaluator *evaluator.Evaluator
+	envs      []*object.Environment
+}
+
+// createEnvironment creates environment i after the environments enclosing
+// it.
+func (dec *decoder) createEnvironment(envs []environment, i int, depth int) error {
+	if dec.envs[i] != nil {
+		return nil
+	}
+	outer := envs[i].Outer
+	if outer == nil {
+		dec.envs[i] = object.NewEnvironment()
+		return nil
+	}
+	if *outer < 0 || *outer >= len(envs) || depth >= len(envs) {
+		return fmt.Errorf("invalid outer environment %d of environment %d", *outer, i)
+	}
+	if err := dec.createEnvironment(envs, *outer, depth+1); err != nil {
+		return err
+	}
+	dec.envs[i] = object.NewEnclosedEnvironment(dec.envs[*outer])
+	return nil
+}
+
+func (dec *decoder) decodeValue(v value) (object.Object, error) {
+	switch v.Type {
+	case object.NULL_OBJ:
+		return object.NULL, nil
+	case object.BOOLEAN_OBJ:
+		if v.Boolean {
+			return object.TRUE, nil
+		}
+		return object.FALSE, nil
+	case object.INTEGER_OBJ:
+		return &object.Integer{Value: v.Integer}, nil
+	case object.FLOAT_OBJ:
+		f, err := strconv.ParseFloat(v.Float, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %q", v.Float)
+		}
+		return &object.Float{Value: f}, nil
+	case object.STRING_OBJ:
+		return &object.String{Value: v.String}, nil
+	case object.REGEX_OBJ:
+		re, err := regexp.Compile(v.String)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %q: %s", v.String, err)
+		}
+		return &object.Regex{Value: re}, nil
+	case object.ARRAY_OBJ:
+		elements := make([]object.Object, len(v.Elements))
+		for i, el := range v.Elements {
+			obj, err := dec.decodeValue(el)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = obj
+		}
+		return &object.Array{Elements: elements}, nil
+	case object.HASH_OBJ:
+		pairs := make(map[object.HashKey]object.HashPair, len(v.Pairs))
+		for _, p := range v.Pairs {
+			key, err := dec.decodeValue(p.Key)
+			if err != nil {
+				return nil, err
+			}
+			hashable, ok := key.(object.Hashable)
+			if !ok {
+				return nil, fmt.Errorf("unusable as hash key: %s", key.Type())
+			}
+			val, err := dec.decodeValue(p.Value)
+			if err != nil {
+				return nil, err
+			}
+			pairs[hashable.HashKey()] = object.HashPair{Key: key, Value: val}
+		}
+		return &object.Hash{Pairs: pairs}, nil
+	case object.FUNCTION_OBJ:
+		return dec.decodeFunction(v)
+	case object.BUILTIN_OBJ:
+		return dec.decodeBuiltin(v)
+	case object.MODULE_OBJ:
+		mod := dec.evaluator.Import(v.Name)
+		if err, ok := mod.(*object.Error); ok {
+			return nil, fmt.Errorf("%s", err.Message)
+		}
+		return mod, nil
+	default:
+		return nil, fmt.Errorf("unsupported value of type %s", v.Type)
+	}
+}
+
+// decodeBuiltin looks a builtin up by name. The builtins of native modules
+// are named after their module, as in math.sqrt, and are looked up among
+// its exports.
+func (dec *decoder) decodeBuiltin(v value) (object.Object, error) {
+	if builtin, ok := dec.evaluator.Builtins[v.Name]; ok {
+		return builtin, nil
+	}
+	if i := strings.LastIndex(v.Name, "."); i > 0 {
+		if mod, ok := dec.evaluator.Import(v.Name[:i]).(*object.Module); ok {
+			if builtin, ok := mod.Exports[v.Name[i+1:]].(*object.Builtin); ok && builtin.Name == v.Name {
+				return builtin, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown builtin %s", v.Name)
+}
+
+func (dec *decoder) decodeFunction(v value) (object.Object, error) {
+	if v.Env == nil || *v.Env < 0 || *v.Env >= len(dec.envs) {
+		return nil, fmt.Errorf("function %s has no valid environment", v.Name)
+	}
+	p := parser.New(lexer.New(v.Source))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("cannot parse function %s: %s", v.Name, p.Errors()[0])
+	}
+	var literal *ast.FunctionLiteral
+	if len(program.Statements) == 1 {
+		if stmt, ok := program.Statements[0].(*ast.ExpressionStatement); ok {
+			literal, _ = stmt.Expression.(*ast.FunctionLiteral)
+		}
+	}
+	if literal == nil {
+		return nil, fmt.Errorf("source of function %s is not a function literal", v.Name)
+	}
+	return &object.Function{
+		Name:        v.Name,
+		Parameters:  literal.Parameters,
+		Rest:        literal.Rest,
+		Body:        literal.Body,
+		Environment: dec.envs[*v.Env],
+	}, nil
+}
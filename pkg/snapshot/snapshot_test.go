@@ -0,0 +1,181 @@
+package snapshot_test
+
+import (
+	"bytes"
+	"github.com/muter3000/monkeparser/pkg/evaluator"
+	"github.com/muter3000/monkeparser/pkg/lexer"
+	"github.com/muter3000/monkeparser/pkg/module"
+	"github.com/muter3000/monkeparser/pkg/object"
+	"github.com/muter3000/monkeparser/pkg/parser"
+	"github.com/muter3000/monkeparser/pkg/snapshot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+const session = `
+let n = 42;
+let ratio = 0.1;
+let huge = 1e308 * 10.0;
+let flag = false;
+let greeting = "hi \"there\"";
+let nothing = null;
+let list = [1, "two", [true]];
+let table = {"a": 1, 2: "b", true: [3]};
+let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } };
+let make_adder = fn(k) { fn(x, scale = 1) { (x + k) * scale } };
+let add_five = make_adder(5);
+let size = len;
+let pick = fn({name}, [first, ..rest]) { match (first) { 1 => name, _ => rest } };
+let sign = fn(x) { let s = if (x < 0) { "-" } else { "+" }; s };
+let scaled = fn(x) { (if (x) { 1 } else { 2 }) * 10 };
+let offset = fn(x) { 100 + if (x) { 1 } else { 2 } };
+let either = fn(x) { [if (x) { "yes" }, if (!x) { "no" }] };
+let classify = fn(x) { match (x) { -1 => "minus one", -1.5 => "minus one and a half", _ => "other" } };
+import "re" as re;
+let digits = re.compile("[0-9]+");
+`
+
+func eval(t *testing.T, input string, env *object.Environment) object.Object {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	require.Empty(t, p.Errors())
+	return evaluator.Eval(program, env)
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	env := object.NewEnvironment()
+	eval(t, session, env)
+
+	var buf bytes.Buffer
+	require.NoError(t, snapshot.Snapshot(&buf, env))
+	saved := buf.String()
+
+	restored, err := snapshot.Restore(strings.NewReader(saved), nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"n", "42"},
+		{"ratio", "0.1"},
+		{"huge", "+Inf"},
+		{"flag", "false"},
+		{"greeting", `hi "there"`},
+		{"nothing == null", "true"},
+		{"list[2][0]", "true"},
+		{"table", "{2: b, a: 1, true: [3]}"},
+		{"fact(10)", "3628800"},
+		{"add_five(1)", "6"},
+		{"add_five(1, scale: 3)", "18"},
+		{"make_adder(1)(1)", "2"},
+		{"size([1, 2])", "2"},
+		{`pick({"name": "ana"}, [1, 2])`, "ana"},
+		{`pick({"name": "ana"}, [2, 3])`, "[3]"},
+		{"sign(-1) + sign(1)", "-+"},
+		{"scaled(false)", "20"},
+		{"offset(true)", "101"},
+		{"either(true)", "[yes, null]"},
+		{"[classify(-1), classify(-1.5), classify(1)]", "[minus one, minus one and a half, other]"},
+		{"digits", `<regex "[0-9]+">`},
+		{`re.split(digits, "a1b22c")`, "[a, b, c]"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, eval(t, tt.input, restored).Inspect(), tt.input)
+	}
+
+	buf.Reset()
+	require.NoError(t, snapshot.Snapshot(&buf, restored))
+	assert.Equal(t, saved, buf.String())
+}
+
+func TestSnapshotEnclosedEnvironment(t *testing.T) {
+	prelude := object.NewEnvironment()
+	eval(t, "let base = 10; let inc = fn(x) { x + base }", prelude)
+	env := object.NewEnclosedEnvironment(prelude)
+	eval(t, "let x = inc(1)", env)
+
+	var buf bytes.Buffer
+	require.NoError(t, snapshot.Snapshot(&buf, env))
+	restored, err := snapshot.Restore(&buf, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"x"}, restored.Names())
+	require.NotNil(t, restored.Outer())
+	assert.Equal(t, []string{"base", "inc"}, restored.Outer().Names())
+	assert.Equal(t, "21", eval(t, "inc(x)", restored).Inspect())
+}
+
+func TestSnapshotModules(t *testing.T) {
+	newEvaluator := func() *evaluator.Evaluator {
+		e := evaluator.New()
+		e.Loader = module.Map{"lib/util.mk": "export let twice = fn(x) { x * 2 };"}
+		return e
+	}
+	env := object.NewEnvironment()
+	p := parser.New(lexer.New(`import "math" as math; let sqrt = math.sqrt; import "lib/util.mk" as util;`))
+	program := p.ParseProgram()
+	require.Empty(t, p.Errors())
+	newEvaluator().Eval(program, env)
+
+	var buf bytes.Buffer
+	require.NoError(t, snapshot.Snapshot(&buf, env))
+	saved := buf.String()
+
+	e := newEvaluator()
+	restored, err := snapshot.RestoreWith(strings.NewReader(saved), e)
+	require.NoError(t, err)
+	for input, expected := range map[string]string{
+		"math.floor(2.5)": "2",
+		"sqrt(16)":        "4.0",
+		"util.twice(4)":   "8",
+	} {
+		assert.Equal(t, expected, e.Eval(parser.New(lexer.New(input)).ParseProgram(), restored).Inspect(), input)
+	}
+	assert.Contains(t, e.Modules, "lib/util.mk")
+
+	_, err = snapshot.Restore(strings.NewReader(saved), nil)
+	assert.EqualError(t, err, "cannot restore util: cannot import lib/util.mk: no module loader")
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	env := object.NewEnvironment()
+	env.Set("err", object.NewError(object.RuntimeError, "boom"))
+	err := snapshot.Snapshot(&bytes.Buffer{}, env)
+	assert.EqualError(t, err, "cannot snapshot err: unsupported value of type ERROR")
+
+	env = object.NewEnvironment()
+	env.Set("f", &object.Builtin{Fn: func(args ...object.Object) object.Object { return nil }})
+	err = snapshot.Snapshot(&bytes.Buffer{}, env)
+	assert.EqualError(t, err, "cannot snapshot f: builtin has no name")
+}
+
+func TestRestoreErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"version": 2, "environments": []}`, "unsupported snapshot version 2"},
+		{`{"version": 1, "environments": []}`, "snapshot has no environments"},
+		{`not json`, "cannot read snapshot: invalid character 'o' in literal null (expecting 'u')"},
+		{`{"version": 1, "environments": [{"outer": 0, "bindings": []}]}`, "invalid outer environment 0 of environment 0"},
+		{`{"version": 1, "environments": [{"bindings": [{"name": "p", "value": {"type": "BUILTIN", "name": "nope"}}]}]}`,
+			"cannot restore p: unknown builtin nope"},
+		{`{"version": 1, "environments": [{"bindings": [{"name": "p", "value": {"type": "BUILTIN", "name": "math.nope"}}]}]}`,
+			"cannot restore p: unknown builtin math.nope"},
+		{`{"version": 1, "environments": [{"bindings": [{"name": "m", "value": {"type": "MODULE", "name": "nope"}}]}]}`,
+			"cannot restore m: cannot import nope: no module loader"},
+		{`{"version": 1, "environments": [{"bindings": [{"name": "r", "value": {"type": "REGEX", "string": "("}}]}]}`,
+			"cannot restore r: invalid regex \"(\": error parsing regexp: missing closing ): `(`"},
+		{`{"version": 1, "environments": [{"bindings": [{"name": "f", "value": {"type": "FUNCTION", "source": "1 +", "env": 0}}]}]}`,
+			"cannot restore f: cannot parse function : no prefix parse function for EOF found"},
+		{`{"version": 1, "environments": [{"bindings": [{"name": "f", "value": {"type": "FUNCTION", "source": "1", "env": 0}}]}]}`,
+			"cannot restore f: source of function  is not a function literal"},
+	}
+	for _, tt := range tests {
+		_, err := snapshot.Restore(strings.NewReader(tt.input), nil)
+		assert.EqualError(t, err, tt.expected)
+	}
+}
@@ -1,20 +1,47 @@
 package token
 
+import "fmt"
+
 type TokenType string
 
+// Position is a 1-based line and column (in bytes) in the source code.
+type Position struct {
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// IsValid reports whether the position was set by the lexer.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position
 }
 
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
-	"true":   TRUE,
-	"false":  FALSE,
+	"fn":      FUNCTION,
+	"let":     LET,
+	"if":      IF,
+	"else":    ELSE,
+	"return":  RETURN,
+	"true":    TRUE,
+	"false":   FALSE,
+	"null":    NULL,
+	"match":   MATCH,
+	"try":     TRY,
+	"catch":   CATCH,
+	"finally": FINALLY,
+	"throw":   THROW,
+	"import":  IMPORT,
+	"export":  EXPORT,
+	"as":      AS,
 }
 
 func LookupIdent(ident string) TokenType {
@@ -29,8 +56,10 @@ const (
 	EOF     = "EOF"
 
 	// Identifiers + literals
-	IDENT = "IDENT" // add, foobar, x, y, ...
-	INT   = "INT"
+	IDENT  = "IDENT" // add, foobar, x, y, ...
+	INT    = "INT"
+	FLOAT  = "FLOAT"
+	STRING = "STRING"
 
 	ASSIGN = "="
 
@@ -39,6 +68,14 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	MATCH    = "MATCH"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
+	THROW    = "THROW"
+	IMPORT   = "IMPORT"
+	EXPORT   = "EXPORT"
+	AS       = "AS"
 
 	PLUS = "+"
 	SUB  = "-"
@@ -57,6 +94,16 @@ const (
 
 	TRUE  = "TRUE"
 	FALSE = "FALSE"
+	NULL  = "NULL"
+
+	NULLISH      = "??"
+	QUESTION     = "?"
+	OPT_DOT      = "?."
+	OPT_LBRACKET = "?["
+	DOT          = "."
+	DOTDOT       = ".."
+	ELLIPSIS     = "..."
+	FAT_ARROW    = "=>"
 
 	SEMICOLON = ";"
 	LPAREN    = "("
@@ -64,4 +111,7 @@ const (
 	LBRACE    = "{"
 	RBRACE    = "}"
 	COMMA     = ","
+	COLON     = ":"
+	LBRACKET  = "["
+	RBRACKET  = "]"
 )
@@ -0,0 +1 @@
+export let greeting = "hello";
@@ -0,0 +1,260 @@
+// Package vfs provides the filesystems that hosts give scripts access to.
+//
+// Everything a script reads, from imported modules to files opened by
+// builtins, goes through an fs.FS configured by the host. The filesystems
+// here are an in-memory one, a jail rooted at a directory of the real disk,
+// and one over files embedded in the host binary. Scripts can only write
+// files when the host grants them a WriteFS.
+package vfs
+
+import (
+	"embed"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrSymlink is returned by a Dir for paths that go through a symbolic link.
+var ErrSymlink = errors.New("symbolic links are not allowed")
+
+// WriteFS is a filesystem that scripts may also write files to.
+type WriteFS interface {
+	fs.FS
+	// WriteFile creates or replaces the named file. The directory it is
+	// in must exist.
+	WriteFile(name string, data []byte) error
+}
+
+// Dir returns a read-only filesystem for the directory tree rooted at root.
+// Unlike os.DirFS it refuses to follow symbolic links, so that scripts
+// cannot reach outside the tree. Names containing ".." elements are
+// rejected like in every fs.FS.
+func Dir(root string) fs.FS {
+	return dirFS{root: root}
+}
+
+type dirFS struct {
+	root string
+}
+
+func (d dirFS) Open(name string) (fs.File, error) {
+	if err := d.check("open", name, true); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filepath.Join(d.root, filepath.FromSlash(name)))
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: unwrapPathError(err)}
+	}
+	return f, nil
+}
+
+// check rejects invalid names and names that go through a symbolic link.
+// It checks every element below the root, so that a link to a directory
+// cannot be used to escape either. A missing last element is allowed
+// unless mustExist is set.
+func (d dirFS) check(op, name string, mustExist bool) error {
+	if !fs.ValidPath(name) || strings.ContainsAny(name, `\:`) {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return nil
+	}
+	elems := strings.Split(name, "/")
+	for i := range elems {
+		info, err := os.Lstat(filepath.Join(d.root, filepath.Join(elems[:i+1]...)))
+		if err != nil {
+			if !mustExist && i == len(elems)-1 && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return &fs.PathError{Op: op, Path: name, Err: unwrapPathError(err)}
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return &fs.PathError{Op: op, Path: name, Err: ErrSymlink}
+		}
+	}
+	return nil
+}
+
+// WritableDir is like Dir, but scripts may also write files in the tree.
+// Writes do not follow symbolic links either.
+func WritableDir(root string) WriteFS {
+	return writableDirFS{dirFS{root: root}}
+}
+
+type writableDirFS struct {
+	dirFS
+}
+
+func (d writableDirFS) WriteFile(name string, data []byte) error {
+	if name == "." {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	if err := d.check("write", name, false); err != nil {
+		return err
+	}
+	err := os.WriteFile(filepath.Join(d.root, filepath.FromSlash(name)), data, 0644)
+	if err != nil {
+		return &fs.PathError{Op: "write", Path: name, Err: unwrapPathError(err)}
+	}
+	return nil
+}
+
+// unwrapPathError strips the host path from errors, so that scripts see
+// only names relative to the root.
+func unwrapPathError(err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err
+	}
+	return err
+}
+
+// Embed returns the files embedded under dir, so that the directory named
+// in the go:embed directive does not appear in the names scripts use.
+func Embed(files embed.FS, dir string) (fs.FS, error) {
+	return fs.Sub(files, dir)
+}
+
+// Memory is a filesystem held in memory. It maps file names to their
+// contents; directories exist implicitly when files are named below them.
+// Names must be valid fs.FS paths, others are ignored. Memory is a WriteFS,
+// but scripts can only write to it when the host grants it as one. It is
+// safe for concurrent use, so that interpreters can share one.
+type Memory struct {
+	mu    sync.RWMutex
+	files map[string]string
+}
+
+// NewMemory returns a Memory holding a copy of files, which maps file names
+// to their contents.
+func NewMemory(files map[string]string) *Memory {
+	m := &Memory{files: make(map[string]string, len(files))}
+	for name, data := range files {
+		m.files[name] = data
+	}
+	return m
+}
+
+// WriteFile stores data under name. Since directories are implicit, any
+// name that is not a directory can be written.
+func (m *Memory) WriteFile(name string, data []byte) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	prefix := name + "/"
+	for file := range m.files {
+		if strings.HasPrefix(file, prefix) {
+			return &fs.PathError{Op: "write", Path: name, Err: errIsDir}
+		}
+	}
+	m.files[name] = string(data)
+	return nil
+}
+
+var errIsDir = errors.New("is a directory")
+
+func (m *Memory) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if data, ok := m.files[name]; ok {
+		return &memFile{
+			Reader: strings.NewReader(data),
+			info:   fileInfo{name: path.Base(name), size: int64(len(data))},
+		}, nil
+	}
+
+	prefix := name + "/"
+	if name == "." {
+		prefix = ""
+	}
+	var entries []fs.DirEntry
+	seen := make(map[string]bool)
+	for file, data := range m.files {
+		if !strings.HasPrefix(file, prefix) || !fs.ValidPath(file) {
+			continue
+		}
+		child, _, isDir := strings.Cut(file[len(prefix):], "/")
+		if seen[child] {
+			continue
+		}
+		seen[child] = true
+		info := fileInfo{name: child, dir: isDir}
+		if !isDir {
+			info.size = int64(len(data))
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+	if len(entries) == 0 && name != "." {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return &memDir{info: fileInfo{name: path.Base(name), dir: true}, entries: entries}, nil
+}
+
+type fileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (fi fileInfo) Name() string       { return fi.name }
+func (fi fileInfo) Size() int64        { return fi.size }
+func (fi fileInfo) ModTime() time.Time { return time.Time{} }
+func (fi fileInfo) IsDir() bool        { return fi.dir }
+func (fi fileInfo) Sys() interface{}   { return nil }
+
+func (fi fileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+type memFile struct {
+	*strings.Reader
+	info fileInfo
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memFile) Close() error               { return nil }
+
+type memDir struct {
+	info    fileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *memDir) Close() error               { return nil }
+
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
@@ -0,0 +1,148 @@
+package vfs_test
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"github.com/muter3000/monkeparser/pkg/vfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+//go:embed testdata
+var testdata embed.FS
+
+func TestMemory(t *testing.T) {
+	fsys := vfs.NewMemory(map[string]string{
+		"main.mk":        `import "lib/a.mk" as a;`,
+		"lib/a.mk":       "export let a = 1;",
+		"lib/util/b.mk":  "export let b = 2;",
+		"/absolute.mk":   "ignored",
+		"lib/../evil.mk": "ignored",
+	})
+	require.NoError(t, fstest.TestFS(fsys, "main.mk", "lib/a.mk", "lib/util/b.mk"))
+
+	data, err := fs.ReadFile(fsys, "lib/a.mk")
+	require.NoError(t, err)
+	assert.Equal(t, "export let a = 1;", string(data))
+
+	_, err = fs.ReadFile(fsys, "lib/missing.mk")
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+	_, err = fs.ReadFile(fsys, "../main.mk")
+	assert.True(t, errors.Is(err, fs.ErrInvalid))
+}
+
+func TestDir(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "lib"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "lib", "a.mk"), []byte("export let a = 1;"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0644))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret"), filepath.Join(root, "link")))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "lib", "dir")))
+
+	fsys := vfs.Dir(root)
+	data, err := fs.ReadFile(fsys, "lib/a.mk")
+	require.NoError(t, err)
+	assert.Equal(t, "export let a = 1;", string(data))
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"link", vfs.ErrSymlink},
+		{"lib/dir/secret", vfs.ErrSymlink},
+		{"../secret", fs.ErrInvalid},
+		{"lib/../../secret", fs.ErrInvalid},
+		{"/etc/passwd", fs.ErrInvalid},
+		{"missing.mk", fs.ErrNotExist},
+	}
+	for _, tt := range tests {
+		_, err := fs.ReadFile(fsys, tt.name)
+		assert.True(t, errors.Is(err, tt.err), "%s: %v", tt.name, err)
+		assert.NotContains(t, err.Error(), root, tt.name)
+	}
+}
+
+func TestEmbed(t *testing.T) {
+	fsys, err := vfs.Embed(testdata, "testdata")
+	require.NoError(t, err)
+	data, err := fs.ReadFile(fsys, "lib/hello.mk")
+	require.NoError(t, err)
+	assert.Equal(t, "export let greeting = \"hello\";\n", string(data))
+}
+
+func TestMemoryWriteFile(t *testing.T) {
+	fsys := vfs.NewMemory(map[string]string{"lib/a.mk": "old"})
+	require.NoError(t, fsys.WriteFile("lib/a.mk", []byte("new")))
+	require.NoError(t, fsys.WriteFile("out/b.txt", []byte("b")))
+	require.NoError(t, fstest.TestFS(fsys, "lib/a.mk", "out/b.txt"))
+	data, err := fs.ReadFile(fsys, "lib/a.mk")
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+
+	err = fsys.WriteFile("lib", []byte("x"))
+	assert.EqualError(t, err, "write lib: is a directory")
+	err = fsys.WriteFile("../x", []byte("x"))
+	assert.True(t, errors.Is(err, fs.ErrInvalid))
+}
+
+func TestMemoryConcurrentWrites(t *testing.T) {
+	fsys := vfs.NewMemory(nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("out/%d.txt", i)
+			assert.NoError(t, fsys.WriteFile(name, []byte("x")))
+			_, err := fs.ReadDir(fsys, "out")
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+	entries, err := fs.ReadDir(fsys, "out")
+	require.NoError(t, err)
+	assert.Len(t, entries, 8)
+}
+
+func TestWritableDir(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "lib"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0644))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret"), filepath.Join(root, "link")))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "dir")))
+
+	fsys := vfs.WritableDir(root)
+	require.NoError(t, fsys.WriteFile("lib/a.txt", []byte("a")))
+	data, err := fs.ReadFile(fsys, "lib/a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(data))
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"link", vfs.ErrSymlink},
+		{"dir/secret", vfs.ErrSymlink},
+		{"dir/new", vfs.ErrSymlink},
+		{"../escape", fs.ErrInvalid},
+		{".", fs.ErrInvalid},
+		{"missing/a.txt", fs.ErrNotExist},
+	}
+	for _, tt := range tests {
+		err := fsys.WriteFile(tt.name, []byte("x"))
+		assert.True(t, errors.Is(err, tt.err), "%s: %v", tt.name, err)
+		assert.NotContains(t, err.Error(), root, tt.name)
+	}
+	data, err = os.ReadFile(filepath.Join(outside, "secret"))
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(data))
+}